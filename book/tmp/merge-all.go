@@ -3,65 +3,917 @@
 package main
 
 import (
+	"bufio"
 	"fmt"
+	"io"
 	"log"
+	"net/url"
 	"os"
+	"path"
+	"path/filepath"
 	"regexp"
+	"sort"
+	"strings"
+	"unicode"
 )
 
+// Níveis dos headings no livro completo. O sumário usa "## 📌 Parte" e
+// "### 🔹 Capítulo"; no livro cada parte abre no nível 1 e as seções
+// ficam abaixo do capítulo, com os headings do arquivo deslocados.
+const (
+	partLevel    = 1
+	chapterLevel = 2
+	sectionLevel = 3
+	maxLevel     = 6
+)
+
+// missingSection substitui o conteúdo de seções ainda não escritas
+const missingSection = "_Esta seção ainda falta ser escrita._"
+
+// Tipos de nó do sumário
+const (
+	partNode = iota
+	chapterNode
+	sectionNode
+	topicNode // item sem link, como os tópicos planejados dos apêndices
+)
+
+// node é uma parte, capítulo ou seção do sumário
+type node struct {
+	Kind     int
+	Title    string
+	Path     string // arquivo da seção, relativo ao sumário
+	Line     int    // linha no sumário
+	Children []*node
+}
+
+// book é o sumário já interpretado
+type book struct {
+	Summary     string // texto do sumário, copiado no início do livro
+	summaryFile string // nome do arquivo do sumário
+	Dir         string // diretório do sumário; base dos caminhos das seções
+	OutDir      string // diretório do livro completo; base dos links reescritos
+	Nodes       []*node
+
+	sections map[string]*node // caminho absoluto do arquivo → seção
+	anchors  map[*node]string // âncora do heading de cada nó no livro
+}
+
+// writers gera o livro no formato indicado pela extensão da saída. Outros
+// formatos se registram em init, em arquivos passados junto para o go run
+// (go run merge-all.go pdf.go ...).
+var writers = map[string]func(b *book, out string) error{
+	".md": (*book).writeBook,
+}
+
+// Expressões usadas para interpretar o sumário
+var (
+	headingRegex  = regexp.MustCompile(`^(#{1,6})\s+(.*?)\s*#*\s*$`)
+	listItemRegex = regexp.MustCompile(`^\s*(?:[-*+]|\d+[.)])\s+(.*)$`)
+	linkRegex     = regexp.MustCompile(`(!?)\[((?:[^\[\]]|\[[^\[\]]*\])+)\]\(([^)\s]+)(?:\s+"[^"]*")?\)`) // aceita [a[b]c]
+	schemeRegex   = regexp.MustCompile(`^[a-zA-Z][a-zA-Z0-9+.-]*:`)
+	htmlAttrRegex = regexp.MustCompile(`\b(src|href)="([^"]*)"`)
+	htmlIDRegex   = regexp.MustCompile(`\b(?:id|name)="([^"]+)"`)
+	refDefRegex   = regexp.MustCompile(`^(\s{0,3}\[[^\]]+\]:\s*)(\S+)(.*)$`)
+)
+
+// imageExts são extensões que nunca são seções, mesmo em links comuns
+var imageExts = map[string]bool{
+	".png": true, ".jpg": true, ".jpeg": true, ".gif": true, ".svg": true, ".webp": true,
+}
+
 func main() {
+	if len(os.Args) == 3 && os.Args[1] == "check" {
+		b, err := parseSummary(os.Args[2])
+		if err != nil {
+			log.Fatalf("Erro ao ler o arquivo %s: %v", os.Args[2], err)
+		}
+		problems, err := b.check()
+		if err != nil {
+			log.Fatalf("Erro ao verificar o livro: %v", err)
+		}
+		if len(problems) > 0 {
+			printProblems(problems)
+			os.Exit(1)
+		}
+		fmt.Println("Nenhum problema encontrado.")
+		return
+	}
+
 	if len(os.Args) < 3 {
-		fmt.Println("Uso: go run merge-all.go <book-summary.md> <book-full.md")
+		fmt.Println("Uso: go run merge-all.go <book-summary.md> <book-full.md>")
+		fmt.Println("     go run merge-all.go pdf.go <book-summary.md> <book-full.pdf>")
+		fmt.Println("     go run merge-all.go check <book-summary.md>")
 		os.Exit(1)
 	}
 	bookSummary := os.Args[1]
 	bookFull := os.Args[2]
 
-	// Lê o conteúdo completo do arquivo principal
-	data, err := os.ReadFile(bookSummary)
+	b, err := parseSummary(bookSummary)
 	if err != nil {
 		log.Fatalf("Erro ao ler o arquivo %s: %v", bookSummary, err)
 	}
-	summaryContent := string(data)
+	if problems, err := b.check(); err == nil && len(problems) > 0 {
+		fmt.Printf("Aviso: %d problemas no livro; rode o modo check para ver a lista\n", len(problems))
+	}
+	if b.OutDir, err = filepath.Abs(filepath.Dir(bookFull)); err != nil {
+		log.Fatalf("Erro ao resolver %s: %v", bookFull, err)
+	}
+
+	write, ok := writers[strings.ToLower(filepath.Ext(bookFull))]
+	if !ok {
+		log.Fatalf("Formato de saída não suportado: %s (para PDF, rode junto com pdf.go)", bookFull)
+	}
+	if err := write(b, bookFull); err != nil {
+		log.Fatalf("Erro ao escrever o arquivo %s: %v", bookFull, err)
+	}
+
+	fmt.Println("Arquivo atualizado com as seções extraídas.")
+}
+
+// parseSummary lê o sumário como uma árvore: headings de nível 2 abrem
+// partes, de nível 3 capítulos, e itens de lista com link são seções
+func parseSummary(summaryPath string) (*book, error) {
+	data, err := os.ReadFile(summaryPath)
+	if err != nil {
+		return nil, err
+	}
+
+	dir, err := filepath.Abs(filepath.Dir(summaryPath))
+	if err != nil {
+		return nil, err
+	}
+	b := &book{
+		Summary:     string(data),
+		summaryFile: filepath.Base(summaryPath),
+		Dir:         dir,
+		OutDir:      dir,
+		sections:    make(map[string]*node),
+	}
+	var part, chapter *node
+
+	// add pendura o nó no nível mais interno aberto
+	add := func(n *node) {
+		switch {
+		case chapter != nil:
+			chapter.Children = append(chapter.Children, n)
+		case part != nil:
+			part.Children = append(part.Children, n)
+		default:
+			b.Nodes = append(b.Nodes, n)
+		}
+	}
+
+	scanner := bufio.NewScanner(strings.NewReader(b.Summary))
+	for lineNo := 1; scanner.Scan(); lineNo++ {
+		line := scanner.Text()
+
+		if m := headingRegex.FindStringSubmatch(line); m != nil {
+			switch len(m[1]) {
+			case 2:
+				part = &node{Kind: partNode, Title: m[2], Line: lineNo}
+				chapter = nil
+				b.Nodes = append(b.Nodes, part)
+			case 3:
+				chapter = &node{Kind: chapterNode, Title: m[2], Line: lineNo}
+				if part != nil {
+					part.Children = append(part.Children, chapter)
+				} else {
+					b.Nodes = append(b.Nodes, chapter)
+				}
+			}
+			continue
+		}
+
+		m := listItemRegex.FindStringSubmatch(line)
+		if m == nil {
+			continue
+		}
+		item := m[1]
+		if title, target, ok := sectionLink(item); ok {
+			n := &node{Kind: sectionNode, Title: title, Path: target, Line: lineNo}
+			add(n)
+			b.sections[b.sectionFile(n)] = n
+		} else if chapter != nil && !linkRegex.MatchString(item) {
+			add(&node{Kind: topicNode, Title: item, Line: lineNo})
+		}
+	}
+	return b, scanner.Err()
+}
 
-	// Expressão regular para encontrar links no formato [Título](caminho)
-	linkRegex := regexp.MustCompile(`\[(?P<title>[^\]]+)\]\((?P<path>[^)]+)\)`)
-	matches := linkRegex.FindAllStringSubmatch(summaryContent, -1)
+// sectionLink retorna o título e o caminho do primeiro link do item se
+// ele apontar para um arquivo Markdown local. Imagens, links externos e
+// âncoras não são seções.
+func sectionLink(item string) (title, target string, ok bool) {
+	m := linkRegex.FindStringSubmatch(item)
+	if m == nil || m[1] == "!" {
+		return "", "", false
+	}
+	title, target = m[2], m[3]
+	if schemeRegex.MatchString(target) || strings.HasPrefix(target, "//") || strings.HasPrefix(target, "#") {
+		return "", "", false
+	}
+	target, _, _ = strings.Cut(target, "#")
+	ext := strings.ToLower(path.Ext(target))
+	if imageExts[ext] || ext != ".md" {
+		return "", "", false
+	}
+	return title, target, true
+}
 
-	var fullContent string
+// sectionFile retorna o caminho absoluto do arquivo da seção
+func (b *book) sectionFile(n *node) string {
+	return filepath.Join(b.Dir, filepath.FromSlash(n.Path))
+}
 
-	for _, match := range matches {
-		sectionTitle := match[1]
-		sectionPath := match[2]
+// writeBook gera o livro completo em Markdown
+func (b *book) writeBook(bookFull string) error {
+	return writeAtomic(bookFull, b.render)
+}
 
-		fullContent += fmt.Sprintf("## %s\n\n", sectionTitle)
-		
-		sectionData, err := os.ReadFile(sectionPath)
+// writeAtomic gera o arquivo em um temporário ao lado de name e só então
+// o renomeia por cima do antigo, para que uma geração com erro não deixe
+// o livro pela metade
+func writeAtomic(name string, write func(w io.Writer) error) (err error) {
+	f, err := os.CreateTemp(filepath.Dir(name), "."+filepath.Base(name)+".*.tmp")
+	if err != nil {
+		return err
+	}
+	defer func() {
 		if err != nil {
-			fullContent += "_Esta seção ainda falta ser escrita._\n\n"
-		} else {
-			fullContent += string(sectionData) + "\n\n"
+			f.Close()
+			os.Remove(f.Name())
 		}
+	}()
+
+	if err := write(f); err != nil {
+		return err
+	}
+	if err := f.Chmod(0644); err != nil {
+		return err
+	}
+	if err := f.Sync(); err != nil {
+		return err
 	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	return os.Rename(f.Name(), name)
+}
 
-	appendContent(bookFull, summaryContent, fullContent)
-	
-	fmt.Println("Arquivo atualizado com as seções extraídas.")
+// render gera o livro em w: o sumário seguido do corpo. O livro é gerado
+// duas vezes; a primeira só descobre a âncora de cada seção, para que a
+// segunda troque os links entre seções por links internos.
+func (b *book) render(w io.Writer) error {
+	b.anchors = make(map[*node]string)
+	if err := b.build(io.Discard); err != nil {
+		return err
+	}
+	return b.build(w)
+}
+
+// build gera o livro uma vez com as âncoras conhecidas até agora
+func (b *book) build(w io.Writer) error {
+	r := &renderer{book: b, out: bufio.NewWriter(w), slugs: newSlugger()}
+	r.writeMarkdown(b.Summary, b.Dir)
+	for _, n := range b.Nodes {
+		r.writeNode(n)
+	}
+	return r.out.Flush()
+}
+
+// renderer acompanha uma geração do livro: a saída, as âncoras já usadas
+// e o nó cujo heading ainda não foi escrito. Erros de escrita ficam
+// guardados no bufio.Writer e aparecem no Flush final.
+type renderer struct {
+	*book
+	out     *bufio.Writer
+	slugs   *slugger
+	pending *node
+}
+
+// heading escreve um heading gerado pelo builder
+func (r *renderer) heading(level int, text string) {
+	r.registerHeading(text)
+	fmt.Fprintf(r.out, "%s %s\n\n", strings.Repeat("#", level), text)
+}
+
+// registerHeading reserva a âncora do heading, como o GitHub faria no
+// livro completo, e a associa ao nó pendente
+func (r *renderer) registerHeading(text string) {
+	anchor := r.slugs.slug(text)
+	if r.pending != nil {
+		r.anchors[r.pending] = anchor
+		r.pending = nil
+	}
+}
+
+// writeNode escreve o nó e seus filhos. Tópicos sem link consecutivos
+// formam uma lista, como no sumário.
+func (r *renderer) writeNode(n *node) {
+	switch n.Kind {
+	case partNode:
+		r.pending = n
+		r.heading(partLevel, n.Title)
+	case chapterNode:
+		r.pending = n
+		r.heading(chapterLevel, n.Title)
+	case sectionNode:
+		r.writeSection(n)
+		return
+	case topicNode:
+		fmt.Fprintf(r.out, "- %s\n", n.Title)
+		return
+	}
+
+	for i, child := range n.Children {
+		r.writeNode(child)
+		// fecha a lista de tópicos antes do próximo bloco
+		last := i == len(n.Children)-1
+		if child.Kind == topicNode && (last || n.Children[i+1].Kind != topicNode) {
+			r.out.WriteString("\n")
+		}
+	}
 }
 
-func appendContent(bookFull, summaryContent, content string) {
-	f, err := os.OpenFile(bookFull, os.O_CREATE|os.O_WRONLY, 0644)
+// writeSection escreve o arquivo da seção com os headings deslocados
+// para ficarem abaixo do capítulo. Se o arquivo abre com um título
+// próprio (um único heading no nível mais alto, na primeira linha), ele
+// vira o heading da seção; senão o título do sumário é usado.
+func (r *renderer) writeSection(n *node) {
+	r.pending = n
+	file := r.sectionFile(n)
+	data, err := os.ReadFile(file)
+	if err != nil {
+		r.heading(sectionLevel, n.Title)
+		fmt.Fprintf(r.out, "%s\n\n", missingSection)
+		return
+	}
+
+	lines := strings.Split(strings.TrimRight(string(data), "\n"), "\n")
+	headings := headingLines(lines)
+
+	top, count := maxLevel+1, 0
+	for _, i := range headings {
+		level := headingLevel(lines[i])
+		if level < top {
+			top, count = level, 0
+		}
+		if level == top {
+			count++
+		}
+	}
+
+	shift := 0
+	switch {
+	case len(headings) == 0:
+		r.heading(sectionLevel, n.Title)
+	case count == 1 && headings[0] == firstContentLine(lines) && headingLevel(lines[headings[0]]) == top:
+		shift = sectionLevel - top
+	default:
+		r.heading(sectionLevel, n.Title)
+		shift = sectionLevel + 1 - top
+	}
+
+	for _, i := range headings {
+		level := min(headingLevel(lines[i])+shift, maxLevel)
+		lines[i] = strings.Repeat("#", level) + strings.TrimLeft(strings.TrimLeft(lines[i], " "), "#")
+	}
+	r.writeMarkdown(strings.Join(lines, "\n"), filepath.Dir(file))
+	r.out.WriteString("\n\n")
+}
+
+// writeMarkdown copia o texto registrando os headings e reescrevendo os
+// links fora do código. base é o diretório do arquivo de origem.
+func (r *renderer) writeMarkdown(text, base string) {
+	lines := strings.Split(text, "\n")
+	code := codeLines(lines)
+	for i, line := range lines {
+		if i > 0 {
+			r.out.WriteString("\n")
+		}
+		if !code[i] {
+			line = mapLinks(line, func(target string) string {
+				return r.rewriteTarget(target, base)
+			})
+			if headingLevel(line) > 0 {
+				r.registerHeading(headingText(line))
+			}
+		}
+		r.out.WriteString(line)
+	}
+}
+
+// mapLinks aplica fn aos destinos de links, imagens, atributos HTML
+// src/href e definições de referência da linha, exceto dentro de `código`
+func mapLinks(line string, fn func(target string) string) string {
+	if m := refDefRegex.FindStringSubmatch(line); m != nil {
+		return m[1] + fn(m[2]) + m[3]
+	}
+
+	line = mapGroup(line, linkRegex, 3, fn)
+	return mapGroup(line, htmlAttrRegex, 2, fn)
+}
+
+// mapGroup aplica fn ao grupo group de cada ocorrência de re que não
+// comece dentro de um trecho de código
+func mapGroup(line string, re *regexp.Regexp, group int, fn func(string) string) string {
+	spans := codeSpans(line)
+	var out strings.Builder
+	last := 0
+	for _, m := range re.FindAllStringSubmatchIndex(line, -1) {
+		if inSpans(m[0], spans) {
+			continue
+		}
+		start, end := m[2*group], m[2*group+1]
+		out.WriteString(line[last:start])
+		out.WriteString(fn(line[start:end]))
+		last = end
+	}
+	out.WriteString(line[last:])
+	return out.String()
+}
+
+// codeSpans retorna as posições dos trechos de código da linha; cada um
+// fecha com a mesma quantidade de crases que o abriu
+func codeSpans(line string) [][2]int {
+	var spans [][2]int
+	for i := 0; i < len(line); {
+		if line[i] != '`' {
+			i++
+			continue
+		}
+		ticks := len(line[i:]) - len(strings.TrimLeft(line[i:], "`"))
+		end := strings.Index(line[i+ticks:], line[i:i+ticks])
+		if end < 0 {
+			i += ticks
+			continue
+		}
+		end += i + 2*ticks
+		spans = append(spans, [2]int{i, end})
+		i = end
+	}
+	return spans
+}
+
+// inSpans informa se a posição está dentro de algum trecho
+func inSpans(pos int, spans [][2]int) bool {
+	for _, s := range spans {
+		if pos >= s[0] && pos < s[1] {
+			return true
+		}
+	}
+	return false
+}
+
+// rewriteTarget ajusta um destino relativo ao arquivo de origem. Links
+// para arquivos de seção viram âncoras no livro; os demais caminhos são
+// recalculados a partir do diretório do livro completo. URLs, âncoras e
+// caminhos absolutos ficam como estão.
+func (r *renderer) rewriteTarget(target, base string) string {
+	if target == "" || strings.HasPrefix(target, "#") || strings.HasPrefix(target, "/") ||
+		schemeRegex.MatchString(target) {
+		return target
+	}
+
+	p, fragment, hasFragment := strings.Cut(target, "#")
+	unescaped, err := url.PathUnescape(p)
+	if err != nil {
+		return target
+	}
+	file := filepath.Join(base, filepath.FromSlash(unescaped))
+
+	if n, ok := r.sections[file]; ok {
+		if hasFragment {
+			return "#" + fragment
+		}
+		if anchor, ok := r.anchors[n]; ok {
+			return "#" + anchor
+		}
+		return target
+	}
+
+	rel, err := filepath.Rel(r.OutDir, file)
 	if err != nil {
-		log.Fatalf("Erro ao abrir o arquivo %s para append: %v", bookFull, err)
+		return target
+	}
+	rebased := (&url.URL{Path: filepath.ToSlash(rel)}).EscapedPath()
+	if hasFragment {
+		rebased += "#" + fragment
+	}
+	return rebased
+}
+
+// headingText retorna o texto do heading ATX, sem os # de abertura e
+// de fechamento
+func headingText(line string) string {
+	text := strings.TrimSpace(strings.TrimLeft(strings.TrimSpace(line), "#"))
+	if trimmed := strings.TrimRight(text, "#"); strings.HasSuffix(trimmed, " ") {
+		text = strings.TrimSpace(trimmed)
+	}
+	return text
+}
+
+// slugger gera âncoras no formato do GitHub: minúsculas, sem pontuação,
+// espaços viram "-" e repetições recebem sufixo -1, -2...
+type slugger struct {
+	seen map[string]int
+}
+
+func newSlugger() *slugger {
+	return &slugger{seen: make(map[string]int)}
+}
+
+// slug retorna a âncora para o texto do heading, registrando-a
+func (s *slugger) slug(text string) string {
+	result := githubAnchor(text)
+	if _, taken := s.seen[result]; taken {
+		base := result
+		for {
+			s.seen[base]++
+			result = fmt.Sprintf("%s-%d", base, s.seen[base])
+			if _, taken := s.seen[result]; !taken {
+				break
+			}
+		}
+	}
+	s.seen[result] = 0
+	return result
+}
+
+// githubAnchor converte o texto de um heading na âncora usada pelo GitHub
+func githubAnchor(text string) string {
+	var b strings.Builder
+	for _, r := range strings.ToLower(strings.TrimSpace(text)) {
+		switch {
+		case unicode.IsLetter(r), unicode.IsDigit(r), unicode.IsMark(r), r == '_', r == '-':
+			b.WriteRune(r)
+		case r == ' ':
+			b.WriteByte('-')
+		}
+	}
+	return b.String()
+}
+
+// codeLines marca as linhas de blocos de código cercados por ``` ou
+// ~~~, incluindo as cercas
+func codeLines(lines []string) []bool {
+	code := make([]bool, len(lines))
+	var open codeFence
+	for i, line := range lines {
+		switch {
+		case open.char != 0:
+			code[i] = true
+			if closesFence(line, open) {
+				open = codeFence{}
+			}
+		default:
+			if fence, _, ok := parseFence(line); ok {
+				code[i] = true
+				open = fence
+			}
+		}
+	}
+	return code
+}
+
+// codeFence é uma cerca de bloco de código: o caractere (` ou ~) e
+// quantas vezes ele se repete
+type codeFence struct {
+	char  byte
+	width int
+}
+
+// parseFence reconhece uma cerca: três ou mais ` ou ~, seguidos de um
+// info string opcional ("```go"). Com crases, o info string não pode ter
+// crase, ou a linha seria código inline. As regras são as do CommonMark,
+// as mesmas do fenceScanner do go-writer.
+func parseFence(line string) (fence codeFence, info string, ok bool) {
+	line = strings.TrimLeft(line, " \t")
+	if line == "" || (line[0] != '`' && line[0] != '~') {
+		return codeFence{}, "", false
 	}
-	defer f.Close()
+	n := 0
+	for n < len(line) && line[n] == line[0] {
+		n++
+	}
+	info = strings.TrimSpace(line[n:])
+	if n < 3 || (line[0] == '`' && strings.Contains(info, "`")) {
+		return codeFence{}, "", false
+	}
+	return codeFence{char: line[0], width: n}, info, true
+}
+
+// closesFence informa se line fecha o bloco aberto por open: mesmo
+// caractere, ao menos do mesmo tamanho e sem info string. Assim um
+// "```go" dentro de um bloco aberto com ```` continua sendo código.
+func closesFence(line string, open codeFence) bool {
+	fence, info, ok := parseFence(line)
+	return ok && fence.char == open.char && fence.width >= open.width && info == ""
+}
+
+// headingLines retorna os índices das linhas com headings ATX fora de
+// blocos de código
+func headingLines(lines []string) []int {
+	var headings []int
+	code := codeLines(lines)
+	for i, line := range lines {
+		if !code[i] && headingLevel(line) > 0 {
+			headings = append(headings, i)
+		}
+	}
+	return headings
+}
+
+// headingLevel retorna o nível do heading ATX da linha, ou 0
+func headingLevel(line string) int {
+	trimmed := strings.TrimLeft(line, " ")
+	if len(line)-len(trimmed) > 3 {
+		return 0 // quatro espaços já é bloco de código
+	}
+	line = trimmed
+	level := 0
+	for level < len(line) && line[level] == '#' {
+		level++
+	}
+	if level == 0 || level > maxLevel {
+		return 0
+	}
+	if level < len(line) && line[level] != ' ' && line[level] != '\t' {
+		return 0
+	}
+	return level
+}
 
-	
-	if _, err := f.WriteString(summaryContent); err != nil {
-		log.Fatalf("Erro ao escrever no arquivo %s: %v", bookFull, err)
+// firstContentLine retorna o índice da primeira linha não vazia
+func firstContentLine(lines []string) int {
+	for i, line := range lines {
+		if strings.TrimSpace(line) != "" {
+			return i
+		}
 	}
+	return -1
+}
+
+// Tipos de problema do modo check, na ordem em que são listados
+const (
+	problemMissing = iota
+	problemDuplicate
+	problemOrphan
+	problemDeadLink
+)
+
+// problemTitles são os cabeçalhos de cada grupo no relatório
+var problemTitles = map[int]string{
+	problemMissing:   "Seções sem arquivo",
+	problemDuplicate: "Seções repetidas no sumário",
+	problemOrphan:    "Arquivos fora do sumário",
+	problemDeadLink:  "Links quebrados",
+}
+
+// problem é um item do relatório do modo check
+type problem struct {
+	Kind    int
+	Where   string // arquivo:linha
+	Message string
+}
 
-	if _, err := f.WriteString(content); err != nil {
-		log.Fatalf("Erro ao escrever no arquivo %s: %v", bookFull, err)
+// check procura seções do sumário sem arquivo, arquivos de capítulo que
+// o sumário não cita e links internos quebrados. Os caminhos do
+// relatório são relativos ao diretório do sumário.
+func (b *book) check() ([]problem, error) {
+	var problems []problem
+	rel := func(file string) string {
+		if r, err := filepath.Rel(b.Dir, file); err == nil {
+			return filepath.ToSlash(r)
+		}
+		return file
+	}
+
+	// seções: arquivos ausentes e repetidos, e os diretórios de capítulos
+	seen := make(map[string]int)
+	roots := make(map[string]bool)
+	orphans := make(map[string]bool)
+	var sections []*node
+	var walk func(nodes []*node)
+	walk = func(nodes []*node) {
+		for _, n := range nodes {
+			if n.Kind == sectionNode {
+				sections = append(sections, n)
+			}
+			walk(n.Children)
+		}
+	}
+	walk(b.Nodes)
+
+	for _, n := range sections {
+		file := b.sectionFile(n)
+		root, _, _ := strings.Cut(filepath.ToSlash(filepath.Clean(n.Path)), "/")
+		if root != ".." && root != filepath.Base(file) {
+			roots[root] = true
+		}
+		if line, ok := seen[file]; ok {
+			problems = append(problems, problem{problemDuplicate, fmt.Sprintf("%s:%d", b.summaryName(), n.Line),
+				fmt.Sprintf("%s já aparece na linha %d", n.Path, line)})
+			continue
+		}
+		seen[file] = n.Line
+	}
+
+	for root := range roots {
+		err := filepath.WalkDir(filepath.Join(b.Dir, root), func(path string, d os.DirEntry, err error) error {
+			if err != nil {
+				return err
+			}
+			if !d.IsDir() && strings.EqualFold(filepath.Ext(path), ".md") {
+				if _, ok := seen[path]; !ok {
+					orphans[path] = true
+				}
+			}
+			return nil
+		})
+		if err != nil && !os.IsNotExist(err) {
+			return nil, err
+		}
+	}
+
+	for _, n := range sections {
+		file := b.sectionFile(n)
+		if _, err := os.Stat(file); err == nil {
+			continue
+		}
+		msg := fmt.Sprintf("%q aponta para %s, que não existe", n.Title, n.Path)
+		if guess := similarFile(file, orphans); guess != "" {
+			msg += "; talvez " + rel(guess)
+		}
+		problems = append(problems, problem{problemMissing, fmt.Sprintf("%s:%d", b.summaryName(), n.Line), msg})
+	}
+	for file := range orphans {
+		problems = append(problems, problem{problemOrphan, rel(file), "não é citado pelo sumário"})
+	}
+
+	// links: o sumário e cada seção existente
+	anchors := make(map[string]map[string]bool)
+	files := []string{filepath.Join(b.Dir, b.summaryName())}
+	for _, n := range sections {
+		files = append(files, b.sectionFile(n))
+	}
+	checked := make(map[string]bool)
+	for _, file := range files {
+		if checked[file] {
+			continue
+		}
+		checked[file] = true
+		data, err := os.ReadFile(file)
+		if err != nil {
+			continue // já listada como seção sem arquivo
+		}
+		isSummary := file == files[0]
+		for _, l := range fileLinks(string(data)) {
+			if msg := b.checkLink(file, l.Target, isSummary, anchors); msg != "" {
+				problems = append(problems, problem{problemDeadLink, fmt.Sprintf("%s:%d", rel(file), l.Line), msg})
+			}
+		}
+	}
+
+	sort.SliceStable(problems, func(i, j int) bool {
+		if problems[i].Kind != problems[j].Kind {
+			return problems[i].Kind < problems[j].Kind
+		}
+		if problems[i].Kind == problemOrphan {
+			return problems[i].Where < problems[j].Where
+		}
+		return false
+	})
+	return problems, nil
+}
+
+// summaryName é o nome do arquivo do sumário, relativo a Dir
+func (b *book) summaryName() string {
+	return b.summaryFile
+}
+
+// link é um destino encontrado em um arquivo, com a linha onde aparece
+type link struct {
+	Line   int
+	Target string
+}
+
+// fileLinks lista os destinos de links fora dos blocos de código
+func fileLinks(text string) []link {
+	var links []link
+	lines := strings.Split(text, "\n")
+	code := codeLines(lines)
+	for i, line := range lines {
+		if code[i] {
+			continue
+		}
+		mapLinks(line, func(target string) string {
+			links = append(links, link{Line: i + 1, Target: target})
+			return target
+		})
+	}
+	return links
+}
+
+// checkLink confere um destino relativo: o arquivo precisa existir e a
+// âncora, se houver, precisa ser um heading ou id do arquivo de destino.
+// Seções do sumário são conferidas à parte, então não se repetem aqui.
+func (b *book) checkLink(from, target string, isSummary bool, anchors map[string]map[string]bool) string {
+	if target == "" || strings.HasPrefix(target, "/") || schemeRegex.MatchString(target) {
+		return ""
+	}
+
+	p, fragment, hasFragment := strings.Cut(target, "#")
+	dest := from
+	if p != "" {
+		unescaped, err := url.PathUnescape(p)
+		if err != nil {
+			return fmt.Sprintf("destino inválido %s", target)
+		}
+		dest = filepath.Join(filepath.Dir(from), filepath.FromSlash(unescaped))
+		if _, isSection := b.sections[dest]; isSection && isSummary {
+			return ""
+		}
+		if _, err := os.Stat(dest); err != nil {
+			return fmt.Sprintf("%s não existe", target)
+		}
+		if _, isSection := b.sections[dest]; !isSection && strings.EqualFold(filepath.Ext(dest), ".md") &&
+			dest != filepath.Join(b.Dir, b.summaryName()) {
+			return fmt.Sprintf("%s não faz parte do livro", target)
+		}
+	}
+
+	if !hasFragment || fragment == "" || !strings.EqualFold(filepath.Ext(dest), ".md") {
+		return ""
+	}
+	if anchors[dest] == nil {
+		data, err := os.ReadFile(dest)
+		if err != nil {
+			return ""
+		}
+		anchors[dest] = fileAnchors(string(data))
+	}
+	if unescaped, err := url.PathUnescape(fragment); err == nil {
+		fragment = unescaped
+	}
+	if !anchors[dest][strings.ToLower(fragment)] {
+		return fmt.Sprintf("âncora #%s não existe em %s", fragment, filepath.Base(dest))
+	}
+	return ""
+}
+
+// fileAnchors retorna as âncoras que o arquivo define sozinho: os
+// headings, no formato do GitHub, e atributos id/name em HTML
+func fileAnchors(text string) map[string]bool {
+	anchors := make(map[string]bool)
+	slugs := newSlugger()
+	lines := strings.Split(text, "\n")
+	code := codeLines(lines)
+	for i, line := range lines {
+		if code[i] {
+			continue
+		}
+		if headingLevel(line) > 0 {
+			anchors[slugs.slug(headingText(line))] = true
+		}
+		for _, m := range htmlIDRegex.FindAllStringSubmatch(line, -1) {
+			anchors[strings.ToLower(m[1])] = true
+		}
+	}
+	return anchors
+}
+
+// similarFile procura entre os órfãos um arquivo com o mesmo nome ou
+// cujo nome termine com o nome procurado (section-4.8.md e
+// ch4-section-4.8.md, por exemplo)
+func similarFile(missing string, orphans map[string]bool) string {
+	base := filepath.Base(missing)
+	var guesses []string
+	for file := range orphans {
+		if strings.HasSuffix(filepath.Base(file), base) {
+			guesses = append(guesses, file)
+		}
+	}
+	sort.Strings(guesses)
+	if len(guesses) == 0 {
+		return ""
+	}
+	return guesses[0]
+}
+
+// printProblems escreve o relatório agrupado por tipo
+func printProblems(problems []problem) {
+	kind := -1
+	for _, p := range problems {
+		if p.Kind != kind {
+			if kind >= 0 {
+				fmt.Println()
+			}
+			kind = p.Kind
+			fmt.Printf("%s:\n", problemTitles[kind])
+		}
+		fmt.Printf("  %s: %s\n", p.Where, p.Message)
 	}
+	fmt.Printf("\n%d problemas encontrados.\n", len(problems))
 }
@@ -0,0 +1,185 @@
+//go:build ignore
+
+// Testes do merge-all.go. Como o programa é rodado com go run, os testes
+// também recebem os arquivos na linha de comando (a partir de book/):
+//
+//	go test tmp/merge-all.go tmp/merge-all_test.go
+//
+// Para regenerar o livro de referência depois de uma mudança intencional:
+//
+//	go test tmp/merge-all.go tmp/merge-all_test.go -update
+
+package main
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+var update = flag.Bool("update", false, "regrava os arquivos de referência em testdata")
+
+// Livro de teste: o sumário e o livro completo esperado, lado a lado como
+// go-bible.md e go-bible-full.md
+const (
+	testSummary = "testdata/livro/livro.md"
+	testGolden  = "testdata/livro/livro-completo.md"
+)
+
+// buildTestBook gera o livro de teste em out. Os links continuam
+// relativos ao diretório do sumário, como se out estivesse ao lado dele.
+func buildTestBook(t *testing.T, out string) []byte {
+	t.Helper()
+	b, err := parseSummary(testSummary)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := b.writeBook(out); err != nil {
+		t.Fatal(err)
+	}
+	data, err := os.ReadFile(out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return data
+}
+
+func TestBuildGolden(t *testing.T) {
+	got := buildTestBook(t, filepath.Join(t.TempDir(), "livro-completo.md"))
+
+	if *update {
+		if err := os.WriteFile(testGolden, got, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	want, err := os.ReadFile(testGolden)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(got, want) {
+		t.Errorf("livro gerado difere de %s; rode com -update se a mudança for intencional\n%s",
+			testGolden, firstDiff(got, want))
+	}
+}
+
+func TestBuildIsReproducible(t *testing.T) {
+	dir := t.TempDir()
+	first := buildTestBook(t, filepath.Join(dir, "a.md"))
+	second := buildTestBook(t, filepath.Join(dir, "b.md"))
+	if !bytes.Equal(first, second) {
+		t.Errorf("duas gerações seguidas diferem\n%s", firstDiff(second, first))
+	}
+}
+
+// Antes da escrita atômica, gerar por cima de um livro maior deixava no
+// fim do arquivo o que sobrava da versão anterior
+func TestBuildOverLongerFile(t *testing.T) {
+	want, err := os.ReadFile(testGolden)
+	if err != nil {
+		t.Fatal(err)
+	}
+	out := filepath.Join(t.TempDir(), "livro-completo.md")
+	stale := append(bytes.Repeat(want, 3), "\n# Seção antiga que não existe mais\n"...)
+	if err := os.WriteFile(out, stale, 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	got := buildTestBook(t, out)
+	if !bytes.Equal(got, want) {
+		t.Errorf("livro gerado sobre um arquivo maior difere de %s (%d bytes, esperados %d)\n%s",
+			testGolden, len(got), len(want), firstDiff(got, want))
+	}
+
+	info, err := os.Stat(out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if perm := info.Mode().Perm(); perm != 0644 {
+		t.Errorf("permissões %v, esperado 0644", perm)
+	}
+	temps, _ := filepath.Glob(filepath.Join(filepath.Dir(out), ".*.tmp"))
+	if len(temps) > 0 {
+		t.Errorf("temporários deixados para trás: %v", temps)
+	}
+}
+
+func TestCheck(t *testing.T) {
+	b, err := parseSummary(testSummary)
+	if err != nil {
+		t.Fatal(err)
+	}
+	problems, err := b.check()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var got []string
+	for _, p := range problems {
+		got = append(got, p.Where)
+	}
+	want := []string{
+		"livro.md:14",                      // seção sem arquivo
+		"livro.md:3",                       // capa.jpg
+		"capitulos/cap3/referencias.md:3",  // img/diagrama.png
+		"capitulos/cap3/referencias.md:5",  // <img src>
+		"capitulos/cap3/referencias.md:11", // definição de referência
+	}
+	if strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Errorf("problemas em\n%s\nesperados em\n%s", strings.Join(got, "\n"), strings.Join(want, "\n"))
+	}
+}
+
+func TestCodeLines(t *testing.T) {
+	tests := []struct {
+		name  string
+		lines []string
+		want  string // c para código, incluindo cercas; . para texto
+	}{
+		{"bloco simples", []string{"a", "```go", "# x", "```", "## b"}, ".ccc."},
+		{"til", []string{"~~~", "x", "~~~", "b"}, "ccc."},
+		{"cerca com info string não fecha", []string{"```", "```go", "x", "```", "b"}, "cccc."},
+		{"cerca menor não fecha", []string{"````", "```", "x", "````", "b"}, "cccc."},
+		{"cerca maior fecha", []string{"```", "x", "`````", "b"}, "ccc."},
+		{"outro caractere não fecha", []string{"~~~", "```", "~~~", "b"}, "ccc."},
+		{"recuada em lista", []string{"1. item", "   ```", "   x", "   ```", "b"}, ".ccc."},
+		{"código inline não é cerca", []string{"```x``` e ``y``", "# b"}, ".."},
+		{"aberto até o fim", []string{"a", "```", "# x"}, ".cc"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got strings.Builder
+			for _, code := range codeLines(tt.lines) {
+				if code {
+					got.WriteByte('c')
+				} else {
+					got.WriteByte('.')
+				}
+			}
+			if got.String() != tt.want {
+				t.Errorf("codeLines = %s, esperado %s", got.String(), tt.want)
+			}
+		})
+	}
+}
+
+// firstDiff descreve a primeira linha em que got e want diferem
+func firstDiff(got, want []byte) string {
+	g, w := strings.Split(string(got), "\n"), strings.Split(string(want), "\n")
+	for i := 0; i < len(g) || i < len(w); i++ {
+		var gl, wl string
+		if i < len(g) {
+			gl = g[i]
+		}
+		if i < len(w) {
+			wl = w[i]
+		}
+		if i >= len(g) || i >= len(w) || gl != wl {
+			return fmt.Sprintf("linha %d:\n  obtido:   %q\n  esperado: %q", i+1, gl, wl)
+		}
+	}
+	return ""
+}
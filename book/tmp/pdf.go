@@ -0,0 +1,1573 @@
+//go:build ignore
+
+// Saída em PDF do merge-all, em Go puro. Rode junto com o merge-all, a
+// partir de book/:
+//
+//	go run tmp/merge-all.go tmp/pdf.go go-bible.md go-bible-full.pdf
+//
+// O livro é gerado em Markdown como no merge-all e então paginado em A4
+// com as fontes padrão do PDF (Helvetica, Courier, Symbol e ZapfDingbats),
+// que todo leitor de PDF traz. Elas só cobrem WinAnsi: alguns símbolos
+// viram equivalentes, os emojis são omitidos e o resto vira "?". Esses
+// caracteres são listados em um aviso durante a geração.
+
+package main
+
+import (
+	"bytes"
+	"compress/zlib"
+	"errors"
+	"fmt"
+	"html"
+	"image"
+	"image/color"
+	_ "image/jpeg"
+	"io"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf16"
+)
+
+func init() {
+	writers[".pdf"] = (*book).writePDF
+}
+
+// coverImage é a capa do livro, no diretório do sumário. Sem ela o PDF
+// começa direto no sumário.
+const coverImage = "go-bible.jpg"
+
+// Página A4 e margens, em pontos
+const (
+	pageWidth    = 595.28
+	pageHeight   = 841.89
+	marginX      = 64.0
+	marginTop    = 64.0
+	marginBottom = 64.0
+	footerY      = 32.0
+	contentWidth = pageWidth - 2*marginX
+)
+
+// Corpos, entrelinhas (múltiplos do corpo) e recuos
+const (
+	bodySize    = 10.5
+	codeSize    = 8.5
+	tableSize   = 9.0
+	footerSize  = 9.0
+	bodyLeading = 1.4
+	codeLeading = 1.3
+	listIndent  = 16.0
+	cellPadding = 4.0
+	codePadding = 6.0
+	codeWrap    = 4 * 600 * codeSize / 1000 // recuo da continuação: quatro colunas
+)
+
+// headingSizes é o corpo de cada nível de heading
+var headingSizes = [...]float64{0, 22, 17, 14, 12, 11, 10.5}
+
+// Cores, como operandos de rg/RG
+const (
+	textColor        = "0.13 0.13 0.13"
+	headingColor     = "0.02 0.25 0.4"
+	linkColor        = "0 0.35 0.7"
+	codeColor        = "0.55 0.1 0.1"
+	codeBackground   = "0.95 0.95 0.95"
+	headerBackground = "0.9 0.92 0.94"
+	ruleColor        = "0.75 0.75 0.75"
+	mutedColor       = "0.45 0.45 0.45"
+)
+
+// writePDF gera o livro em PDF: a capa, o livro em Markdown paginado e o
+// sumário do leitor de PDF com as partes, capítulos e seções
+func (b *book) writePDF(out string) error {
+	var md bytes.Buffer
+	if err := b.render(&md); err != nil {
+		return err
+	}
+
+	// as fontes padrão só cobrem WinAnsi: o que ficar de fora some do PDF
+	if missing := missingGlyphs(md.String()); len(missing) > 0 {
+		fmt.Printf("Aviso: %d caracteres sem glifo nas fontes do PDF (emojis são omitidos, os demais viram \"?\"): %s\n",
+			len(missing), formatGlyphs(missing))
+	}
+
+	w := &pdfWriter{dests: make(map[string]pdfDest), breakAt: make(map[string]bool)}
+	cover, err := os.ReadFile(filepath.Join(b.Dir, coverImage))
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+	if cover != nil {
+		if err := w.cover(cover); err != nil {
+			return err
+		}
+	}
+
+	// partes e capítulos abrem página
+	var walk func(nodes []*node)
+	walk = func(nodes []*node) {
+		for _, n := range nodes {
+			if n.Kind == partNode || n.Kind == chapterNode {
+				w.breakAt[b.anchors[n]] = true
+			}
+			walk(n.Children)
+		}
+	}
+	walk(b.Nodes)
+
+	w.newPage()
+	for _, bl := range parseBlocks(md.String()) {
+		w.block(bl)
+	}
+
+	title := ""
+	for _, line := range strings.Split(b.Summary, "\n") {
+		if headingLevel(line) > 0 {
+			title = plainText(headingText(line))
+			break
+		}
+	}
+	outline := b.outline(w.dests)
+	return writeAtomic(out, func(f io.Writer) error {
+		return w.write(f, outline, title)
+	})
+}
+
+// Tipos de bloco do Markdown do livro
+const (
+	paragraphBlock = iota
+	summaryBlock   // <summary> dos desafios, em negrito
+	headingBlock
+	itemBlock
+	codeBlock
+	tableBlock
+	quoteBlock
+	ruleBlock
+)
+
+// block é um bloco do livro em Markdown
+type block struct {
+	Kind   int
+	Level  int        // heading: nível
+	Depth  int        // item: profundidade na lista; demais: recuo em níveis de lista
+	Marker string     // item: "•" ou o número
+	Text   string     // texto inline
+	Anchor string     // heading: âncora, calculada na mesma ordem do renderer
+	Lines  []string   // código
+	Rows   [][]string // tabela; a primeira linha é o cabeçalho
+}
+
+// Expressões usadas para separar os blocos
+var (
+	itemRegex      = regexp.MustCompile(`^(\s*)([-*+]|[0-9]+[.)])\s+(.*)$`)
+	ruleRegex      = regexp.MustCompile(`^\s{0,3}(-{3,}|\*{3,}|_{3,})\s*$`)
+	tableSepRegex  = regexp.MustCompile(`^\s*\|?\s*:?-+:?\s*(\|\s*:?-+:?\s*)*\|?\s*$`)
+	htmlBlockRegex = regexp.MustCompile(`^\s*</?(details|summary|div|a|img|p|br)\b`)
+)
+
+// parseBlocks separa o livro em blocos. Cercas de código e headings são
+// reconhecidos como em codeLines e headingLevel, para que as âncoras
+// saiam iguais às do renderer.
+func parseBlocks(text string) []block {
+	lines := strings.Split(text, "\n")
+	slugs := newSlugger()
+	var blocks []block
+	var lists []int    // coluna do marcador de cada item aberto
+	inDetails := false // dentro de <details>: a resposta fica recuada
+
+	// within retorna a profundidade de um bloco recuado: dentro do último
+	// item cujo marcador fica à esquerda dele, ou fora das listas
+	within := func(indent int) int {
+		for len(lists) > 0 && lists[len(lists)-1] >= indent {
+			lists = lists[:len(lists)-1]
+		}
+		if inDetails {
+			return max(len(lists), 1)
+		}
+		return len(lists)
+	}
+
+	for i := 0; i < len(lines); {
+		line := lines[i]
+		trimmed := strings.TrimSpace(line)
+		indent := len(line) - len(strings.TrimLeft(line, " "))
+
+		switch {
+		case trimmed == "":
+			i++
+
+		case isFence(line):
+			fence, _, _ := parseFence(line)
+			bl := block{Kind: codeBlock, Depth: within(indent)}
+			for i++; i < len(lines) && !closesFence(lines[i], fence); i++ {
+				bl.Lines = append(bl.Lines, trimIndent(lines[i], indent))
+			}
+			blocks = append(blocks, bl)
+			i++
+
+		case headingLevel(line) > 0:
+			lists = nil
+			text := headingText(line)
+			blocks = append(blocks, block{Kind: headingBlock, Level: headingLevel(line), Text: text, Anchor: slugs.slug(text)})
+			i++
+
+		case refDefRegex.MatchString(line):
+			i++ // definições de referência não aparecem no texto
+
+		case ruleRegex.MatchString(line):
+			lists = nil
+			blocks = append(blocks, block{Kind: ruleBlock})
+			i++
+
+		case isTableStart(lines, i):
+			bl := block{Kind: tableBlock, Depth: within(indent), Rows: [][]string{tableCells(line)}}
+			for i += 2; i < len(lines) && strings.HasPrefix(strings.TrimSpace(lines[i]), "|"); i++ {
+				bl.Rows = append(bl.Rows, tableCells(lines[i]))
+			}
+			blocks = append(blocks, bl)
+
+		case strings.HasPrefix(trimmed, ">"):
+			bl := block{Kind: quoteBlock, Depth: within(indent)}
+			var quote []string
+			for ; i < len(lines) && strings.HasPrefix(strings.TrimSpace(lines[i]), ">"); i++ {
+				quote = append(quote, strings.TrimSpace(strings.TrimPrefix(strings.TrimSpace(lines[i]), ">")))
+			}
+			bl.Text = strings.Join(quote, " ")
+			blocks = append(blocks, bl)
+
+		case itemRegex.MatchString(line):
+			m := itemRegex.FindStringSubmatch(line)
+			for len(lists) > 0 && indent < lists[len(lists)-1] {
+				lists = lists[:len(lists)-1]
+			}
+			if len(lists) == 0 || indent > lists[len(lists)-1] {
+				lists = append(lists, indent)
+			}
+			marker := m[2]
+			if marker == "-" || marker == "*" || marker == "+" {
+				marker = "•"
+			}
+			bl := block{Kind: itemBlock, Depth: len(lists) - 1, Marker: marker}
+			bl.Text, i = paragraphText(lines, i, m[3])
+			blocks = append(blocks, bl)
+
+		case htmlBlockRegex.MatchString(line):
+			kind, depth := paragraphBlock, within(indent)
+			switch {
+			case strings.HasPrefix(trimmed, "<details"):
+				inDetails = true
+			case strings.HasPrefix(trimmed, "</details"):
+				inDetails = false
+			case strings.HasPrefix(trimmed, "<summary"):
+				kind, depth = summaryBlock, 0
+			}
+			blocks = append(blocks, block{Kind: kind, Depth: depth, Text: trimmed})
+			i++
+
+		default:
+			bl := block{Kind: paragraphBlock, Depth: within(indent)}
+			bl.Text, i = paragraphText(lines, i, trimmed)
+			blocks = append(blocks, bl)
+		}
+	}
+	return blocks
+}
+
+// paragraphText junta as linhas do parágrafo que começa em lines[i], cujo
+// texto já interpretado é first, até a linha em branco ou o próximo bloco.
+// Linhas terminadas em dois espaços ou \ forçam a quebra de linha.
+func paragraphText(lines []string, i int, first string) (string, int) {
+	var b strings.Builder
+	text := first
+	for {
+		raw := lines[i]
+		hard := strings.HasSuffix(raw, "  ") || strings.HasSuffix(raw, `\`)
+		b.WriteString(strings.TrimSuffix(strings.TrimSpace(text), `\`))
+		i++
+		if i >= len(lines) || strings.TrimSpace(lines[i]) == "" || startsBlock(lines, i) {
+			return b.String(), i
+		}
+		if hard {
+			b.WriteByte('\n')
+		} else {
+			b.WriteByte(' ')
+		}
+		text = lines[i]
+	}
+}
+
+// startsBlock informa se lines[i] abre um bloco e encerra o parágrafo
+func startsBlock(lines []string, i int) bool {
+	line := lines[i]
+	trimmed := strings.TrimSpace(line)
+	return isFence(line) ||
+		headingLevel(line) > 0 || ruleRegex.MatchString(line) || isTableStart(lines, i) ||
+		strings.HasPrefix(trimmed, ">") || itemRegex.MatchString(line) || htmlBlockRegex.MatchString(line)
+}
+
+// isFence informa se line é uma cerca de bloco de código
+func isFence(line string) bool {
+	_, _, ok := parseFence(line)
+	return ok
+}
+
+// isTableStart informa se lines[i] é o cabeçalho de uma tabela
+func isTableStart(lines []string, i int) bool {
+	return strings.HasPrefix(strings.TrimSpace(lines[i]), "|") &&
+		i+1 < len(lines) && tableSepRegex.MatchString(lines[i+1])
+}
+
+// tableCells separa as células de uma linha de tabela. Barras escapadas
+// ou dentro de `código` não separam células.
+func tableCells(line string) []string {
+	line = strings.TrimPrefix(strings.TrimSpace(line), "|")
+	if strings.HasSuffix(line, "|") && !strings.HasSuffix(line, `\|`) {
+		line = line[:len(line)-1]
+	}
+
+	var cells []string
+	start, inCode := 0, false
+	for i := 0; i < len(line); i++ {
+		switch line[i] {
+		case '\\':
+			i++
+		case '`':
+			inCode = !inCode
+		case '|':
+			if !inCode {
+				cells = append(cells, strings.TrimSpace(line[start:i]))
+				start = i + 1
+			}
+		}
+	}
+	return append(cells, strings.TrimSpace(line[start:]))
+}
+
+// trimIndent remove até n espaços do início da linha
+func trimIndent(line string, n int) string {
+	i := 0
+	for i < n && i < len(line) && line[i] == ' ' {
+		i++
+	}
+	return line[i:]
+}
+
+// span é um trecho de texto inline com um único estilo
+type span struct {
+	Text   string
+	Bold   bool
+	Italic bool
+	Code   bool
+	Link   string
+	Break  bool // quebra de linha forçada
+}
+
+// Expressões da marcação inline
+var (
+	linkAtRegex  = regexp.MustCompile(`^` + linkRegex.String())
+	htmlTagRegex = regexp.MustCompile(`^<(/?)([a-zA-Z]+)\b[^>]*>`)
+	entityRegex  = regexp.MustCompile(`^&(#[0-9]+|#[xX][0-9a-fA-F]+|[a-zA-Z]+);`)
+)
+
+// inlineTags são as tags HTML interpretadas no texto; as demais, como
+// <nome>, ficam como texto
+var inlineTags = map[string]bool{
+	"a": true, "b": true, "br": true, "details": true, "div": true, "em": true, "i": true,
+	"img": true, "p": true, "span": true, "strong": true, "sub": true, "summary": true, "sup": true,
+}
+
+// parseInline interpreta a marcação inline: ênfase, código, links,
+// imagens (pelo texto alternativo), tags HTML simples e entidades
+func parseInline(s string) []span {
+	var p inlineParser
+	p.parse(s, span{})
+	return p.spans
+}
+
+// inlineParser acumula os trechos e o estilo em vigor
+type inlineParser struct {
+	spans []span
+	cur   span
+	text  strings.Builder
+}
+
+// flush fecha o trecho em andamento com o estilo atual
+func (p *inlineParser) flush() {
+	if p.text.Len() > 0 {
+		sp := p.cur
+		sp.Text = p.text.String()
+		p.spans = append(p.spans, sp)
+		p.text.Reset()
+	}
+}
+
+// emit acrescenta um trecho com estilo próprio
+func (p *inlineParser) emit(sp span) {
+	p.flush()
+	p.spans = append(p.spans, sp)
+}
+
+// parse interpreta s a partir do estilo style, que é restaurado no fim
+func (p *inlineParser) parse(s string, style span) {
+	p.flush()
+	saved := p.cur
+	p.cur = style
+	for i := 0; i < len(s); {
+		c := s[i]
+		switch {
+		case c == '\\' && i+1 < len(s) && strings.IndexByte("!\"#$%&'()*+,-./:;<=>?@[\\]^_`{|}~", s[i+1]) >= 0:
+			p.text.WriteByte(s[i+1])
+			i += 2
+			continue
+
+		case c == '\n':
+			p.emit(span{Break: true})
+			i++
+			continue
+
+		case c == '`':
+			n := runLength(s[i:], '`')
+			if end := strings.Index(s[i+n:], strings.Repeat("`", n)); end >= 0 {
+				code := p.cur
+				code.Code = true
+				code.Text = strings.TrimSpace(s[i+n : i+n+end])
+				p.emit(code)
+				i += 2*n + end
+				continue
+			}
+			p.text.WriteString(s[i : i+n])
+			i += n
+			continue
+
+		case c == '[' || c == '!' && strings.HasPrefix(s[i+1:], "["):
+			if m := linkAtRegex.FindStringSubmatchIndex(s[i:]); m != nil {
+				inner := p.cur
+				if m[3] > m[2] { // imagem: só o texto alternativo
+					inner.Italic = true
+				} else {
+					inner.Link = s[i+m[6] : i+m[7]]
+				}
+				p.parse(s[i+m[4]:i+m[5]], inner)
+				i += m[1]
+				continue
+			}
+
+		case c == '<':
+			if m := htmlTagRegex.FindStringSubmatch(s[i:]); m != nil && inlineTags[strings.ToLower(m[2])] {
+				switch strings.ToLower(m[2]) {
+				case "br":
+					p.emit(span{Break: true})
+				case "strong", "b":
+					p.flush()
+					p.cur.Bold = m[1] == ""
+				case "em", "i":
+					p.flush()
+					p.cur.Italic = m[1] == ""
+				}
+				i += len(m[0])
+				continue
+			}
+
+		case c == '&':
+			if m := entityRegex.FindString(s[i:]); m != "" {
+				p.text.WriteString(html.UnescapeString(m))
+				i += len(m)
+				continue
+			}
+
+		case c == '*' || c == '_':
+			n := runLength(s[i:], c)
+			if !p.emphasis(s, i, n) {
+				p.text.WriteString(s[i : i+n])
+			}
+			i += n
+			continue
+		}
+		p.text.WriteByte(c)
+		i++
+	}
+	p.flush()
+	p.cur = saved
+}
+
+// emphasis abre ou fecha o negrito (**) e o itálico (*) conforme o estilo
+// atual. Só abre se o fechamento aparecer mais adiante, e _ no meio de
+// uma palavra, como em snake_case, é texto.
+func (p *inlineParser) emphasis(s string, i, n int) bool {
+	if n > 3 {
+		return false
+	}
+	prev, next := byte(' '), byte(' ')
+	if i > 0 {
+		prev = s[i-1]
+	}
+	if i+n < len(s) {
+		next = s[i+n]
+	}
+	if s[i] == '_' && isWordByte(prev) && isWordByte(next) {
+		return false
+	}
+
+	bold, italic := n >= 2, n != 2
+	closing := prev != ' ' && (!bold || p.cur.Bold) && (!italic || p.cur.Italic)
+	if !closing && (next == ' ' || !strings.Contains(s[i+n:], s[i:i+n])) {
+		return false
+	}
+	p.flush()
+	if bold {
+		p.cur.Bold = !closing
+	}
+	if italic {
+		p.cur.Italic = !closing
+	}
+	return true
+}
+
+// runLength conta quantas vezes c se repete no início de s
+func runLength(s string, c byte) int {
+	n := 0
+	for n < len(s) && s[n] == c {
+		n++
+	}
+	return n
+}
+
+// isWordByte informa se c faz parte de uma palavra (letras acentuadas
+// incluídas, pelos bytes acima de 0x7f)
+func isWordByte(c byte) bool {
+	return c >= 0x80 || c == '_' || '0' <= c && c <= '9' || 'a' <= c && c <= 'z' || 'A' <= c && c <= 'Z'
+}
+
+// plainText tira a marcação e os emojis do texto, para os títulos do
+// sumário do leitor e os metadados
+func plainText(s string) string {
+	var b strings.Builder
+	for _, sp := range parseInline(s) {
+		b.WriteString(sp.Text)
+		b.WriteByte(' ')
+	}
+	return strings.Join(strings.FieldsFunc(b.String(), func(r rune) bool {
+		return unicode.IsSpace(r) || isEmoji(r)
+	}), " ")
+}
+
+// pdfFont é uma das fontes padrão do PDF
+type pdfFont int
+
+const (
+	fontRegular pdfFont = iota
+	fontBold
+	fontItalic
+	fontBoldItalic
+	fontCode
+	fontDingbats
+	fontSymbol
+)
+
+// fontNames são os nomes das fontes, na ordem de pdfFont; no conteúdo a
+// fonte f é /F<f+1>
+var fontNames = [...]string{
+	"Helvetica", "Helvetica-Bold", "Helvetica-Oblique", "Helvetica-BoldOblique",
+	"Courier", "ZapfDingbats", "Symbol",
+}
+
+// width retorna a largura do código c na fonte, em milésimos do corpo
+func (f pdfFont) width(c byte) float64 {
+	switch {
+	case f == fontCode:
+		return 600
+	case f == fontDingbats:
+		return dingbatWidths[c]
+	case f == fontSymbol:
+		return symbolWidths[c]
+	case c < 32:
+		return 0
+	case f == fontBold || f == fontBoldItalic:
+		return float64(helveticaBoldWidths[c-32])
+	default:
+		return float64(helveticaWidths[c-32])
+	}
+}
+
+// textStyle é a fonte base e o corpo de um bloco de texto
+type textStyle struct {
+	Size   float64
+	Bold   bool
+	Italic bool
+}
+
+// font escolhe a fonte do trecho dentro de um bloco com o estilo base
+func (sp span) font(base textStyle) pdfFont {
+	bold, italic := sp.Bold || base.Bold, sp.Italic || base.Italic
+	switch {
+	case sp.Code:
+		return fontCode
+	case bold && italic:
+		return fontBoldItalic
+	case bold:
+		return fontBold
+	case italic:
+		return fontItalic
+	}
+	return fontRegular
+}
+
+// dingbats são os símbolos desenhados com a ZapfDingbats
+var dingbats = map[rune]byte{
+	'✓': 0x33, '✔': 0x34, '✅': 0x34, '✕': 0x35, '✖': 0x36, '✗': 0x37, '✘': 0x38, '❌': 0x38,
+}
+
+var dingbatWidths = map[byte]float64{0x33: 760, 0x34: 846, 0x35: 762, 0x36: 761, 0x37: 571, 0x38: 677}
+
+// symbols são os símbolos desenhados com a fonte Symbol
+var symbols = map[rune]byte{
+	'→': 0xAE, '➡': 0xAE, '←': 0xAC, '↑': 0xAD, '↓': 0xAF, '⇒': 0xDE,
+	'≠': 0xB9, '≤': 0xA3, '≥': 0xB3, '∞': 0xA5,
+}
+
+var symbolWidths = map[byte]float64{
+	0xAE: 987, 0xAC: 987, 0xAD: 603, 0xAF: 603, 0xDE: 987,
+	0xB9: 549, 0xA3: 549, 0xB3: 549, 0xA5: 713,
+}
+
+// winAnsi são os caracteres de 0x80 a 0x9f do WinAnsi; de 0xa0 a 0xff ele
+// coincide com o Latin-1
+var winAnsi = map[rune]byte{
+	'€': 0x80, '‚': 0x82, 'ƒ': 0x83, '„': 0x84, '…': 0x85, '†': 0x86, '‡': 0x87, 'ˆ': 0x88,
+	'‰': 0x89, 'Š': 0x8A, '‹': 0x8B, 'Œ': 0x8C, 'Ž': 0x8E, '‘': 0x91, '’': 0x92, '“': 0x93,
+	'”': 0x94, '•': 0x95, '–': 0x96, '—': 0x97, '˜': 0x98, '™': 0x99, 'š': 0x9A, '›': 0x9B,
+	'œ': 0x9C, 'ž': 0x9E, 'Ÿ': 0x9F,
+}
+
+// replacements troca caracteres sem glifo nas fontes padrão por
+// equivalentes, como as molduras dos diagramas em texto
+var replacements = map[rune]string{
+	'─': "-", '━': "-", '│': "|", '┃': "|", '┌': "+", '┐': "+", '└': "+", '┘': "+",
+	'├': "+", '┤': "+", '┬': "+", '┴': "+", '┼': "+",
+	'❝': "“", '❞': "”", 'ℹ': "i", '🔟': "10",
+}
+
+// encodeRune retorna a fonte e os bytes que desenham r no lugar da fonte
+// font. Emojis voltam vazios; o que não tiver equivalente vira "?".
+func encodeRune(r rune, font pdfFont) (pdfFont, string) {
+	if r < 0x80 || 0xA0 <= r && r <= 0xFF {
+		return font, string([]byte{byte(r)})
+	}
+	if c, ok := winAnsi[r]; ok {
+		return font, string([]byte{c})
+	}
+	if c, ok := dingbats[r]; ok {
+		return fontDingbats, string([]byte{c})
+	}
+	if c, ok := symbols[r]; ok {
+		return fontSymbol, string([]byte{c})
+	}
+	if s, ok := replacements[r]; ok {
+		var b strings.Builder
+		for _, c := range s {
+			_, enc := encodeRune(c, font)
+			b.WriteString(enc)
+		}
+		return font, b.String()
+	}
+	if isEmoji(r) {
+		return font, ""
+	}
+	return font, "?"
+}
+
+// isEmoji informa se r é emoji, pictograma ou um dos modificadores que os
+// acompanham
+func isEmoji(r rune) bool {
+	return r >= 0x1F000 || 0x2300 <= r && r <= 0x23FF || 0x2600 <= r && r <= 0x27BF ||
+		0x2B00 <= r && r <= 0x2BFF || isEmojiModifier(r)
+}
+
+// isEmojiModifier informa se r só modifica o emoji anterior: seletor de
+// variação, ZWJ ou moldura de tecla
+func isEmojiModifier(r rune) bool {
+	return 0xFE00 <= r && r <= 0xFE0F || r == 0x200D || r == 0x20E3
+}
+
+// missingGlyphs conta os caracteres do texto que as fontes do PDF não
+// desenham: os emojis, omitidos, e o que sai como "?". Modificadores de
+// emoji não contam; somem junto com o emoji.
+func missingGlyphs(text string) map[rune]int {
+	missing := make(map[rune]int)
+	for _, r := range text {
+		if r < 0x80 || unicode.IsSpace(r) || isEmojiModifier(r) {
+			continue
+		}
+		if _, enc := encodeRune(r, fontRegular); enc == "" || strings.Contains(enc, "?") {
+			missing[r]++
+		}
+	}
+	return missing
+}
+
+// formatGlyphs lista os caracteres em ordem, com o código e quantas vezes
+// aparecem: "言 U+8A00 ×2, ► U+25BA ×1"
+func formatGlyphs(glyphs map[rune]int) string {
+	runes := make([]rune, 0, len(glyphs))
+	for r := range glyphs {
+		runes = append(runes, r)
+	}
+	slices.Sort(runes)
+	list := make([]string, len(runes))
+	for i, r := range runes {
+		list[i] = fmt.Sprintf("%c U+%04X ×%d", r, r, glyphs[r])
+	}
+	return strings.Join(list, ", ")
+}
+
+// pdfRun é um trecho já codificado para uma fonte
+type pdfRun struct {
+	Font  pdfFont
+	Size  float64
+	Text  []byte
+	Width float64
+	Link  string
+	Code  bool // código inline, desenhado com fundo
+}
+
+// pdfWord é uma palavra pronta para a quebra de linhas. Space é a largura
+// do espaço antes dela; Break força uma quebra de linha.
+type pdfWord struct {
+	Runs  []pdfRun
+	Width float64
+	Space float64
+	Break bool
+}
+
+// add acrescenta o trecho à palavra, juntando-o ao anterior se o estilo
+// for o mesmo
+func (w *pdfWord) add(r pdfRun) {
+	w.Width += r.Width
+	if n := len(w.Runs); n > 0 {
+		last := &w.Runs[n-1]
+		if last.Font == r.Font && last.Size == r.Size && last.Link == r.Link && last.Code == r.Code {
+			last.Text = append(last.Text, r.Text...)
+			last.Width += r.Width
+			return
+		}
+	}
+	w.Runs = append(w.Runs, r)
+}
+
+// textWidth mede bytes já codificados na fonte e no corpo dados
+func textWidth(font pdfFont, text string, size float64) float64 {
+	width := 0.0
+	for i := 0; i < len(text); i++ {
+		width += font.width(text[i])
+	}
+	return width * size / 1000
+}
+
+// words divide os trechos em palavras medidas. Espaços dentro de
+// `código` não quebram a linha.
+func words(spans []span, base textStyle) []pdfWord {
+	var out []pdfWord
+	var cur pdfWord
+	space := 0.0
+	end := func() {
+		if len(cur.Runs) > 0 {
+			cur.Space = space
+			out = append(out, cur)
+			space = 0
+		}
+		cur = pdfWord{}
+	}
+
+	for _, sp := range spans {
+		if sp.Break {
+			end()
+			out = append(out, pdfWord{Break: true})
+			space = 0
+			continue
+		}
+		font := sp.font(base)
+		for _, r := range sp.Text {
+			if unicode.IsSpace(r) {
+				if !sp.Code {
+					end()
+					space = font.width(' ') * base.Size / 1000
+					continue
+				}
+				r = ' '
+			}
+			f, enc := encodeRune(r, font)
+			if enc != "" {
+				cur.add(pdfRun{Font: f, Size: base.Size, Text: []byte(enc), Width: textWidth(f, enc, base.Size), Link: sp.Link, Code: sp.Code})
+			}
+		}
+	}
+	end()
+	return out
+}
+
+// pdfLine é uma linha de palavras já quebrada
+type pdfLine struct {
+	Words  []pdfWord
+	Width  float64
+	Indent float64 // recuo extra, nas continuações de linhas de código
+}
+
+// breakLines distribui as palavras em linhas de até width pontos
+func breakLines(ws []pdfWord, width float64) []pdfLine {
+	var lines []pdfLine
+	var cur pdfLine
+	for _, w := range ws {
+		if w.Break {
+			lines = append(lines, cur)
+			cur = pdfLine{}
+			continue
+		}
+		for _, part := range splitWord(w, width) {
+			if len(cur.Words) > 0 && cur.Width+part.Space+part.Width > width {
+				lines = append(lines, cur)
+				cur = pdfLine{}
+			}
+			if len(cur.Words) == 0 {
+				part.Space = 0
+			}
+			cur.Words = append(cur.Words, part)
+			cur.Width += part.Space + part.Width
+		}
+	}
+	if len(cur.Words) > 0 || len(lines) == 0 {
+		lines = append(lines, cur)
+	}
+	return lines
+}
+
+// splitWord corta por caractere uma palavra mais larga que width
+func splitWord(w pdfWord, width float64) []pdfWord {
+	if w.Width <= width {
+		return []pdfWord{w}
+	}
+	var parts []pdfWord
+	cur := pdfWord{Space: w.Space}
+	for _, r := range w.Runs {
+		for i := 0; i < len(r.Text); i++ {
+			c := r
+			c.Text = []byte{r.Text[i]}
+			c.Width = r.Font.width(r.Text[i]) * r.Size / 1000
+			if cur.Width > 0 && cur.Width+c.Width > width {
+				parts = append(parts, cur)
+				cur = pdfWord{}
+			}
+			cur.add(c)
+		}
+	}
+	return append(parts, cur)
+}
+
+// pdfWriter pagina o livro. y é a altura, em coordenadas do PDF (de baixo
+// para cima), onde começa o espaço livre da página atual.
+type pdfWriter struct {
+	pages   []*pdfPage
+	page    *pdfPage
+	y       float64
+	first   int // índice da página numerada como 1: 1 com capa, 0 sem
+	prev    int // tipo do bloco anterior, para o espaçamento
+	dests   map[string]pdfDest
+	breakAt map[string]bool // âncoras que abrem página: partes e capítulos
+
+	coverData  []byte
+	coverSize  image.Point
+	coverSpace string
+}
+
+// pdfPage é o conteúdo de uma página e os links dela
+type pdfPage struct {
+	content bytes.Buffer
+	links   []pdfLink
+}
+
+// pdfDest é um destino de link: a página e a altura do topo do heading
+type pdfDest struct {
+	Page int
+	Y    float64
+}
+
+// pdfLink é a área clicável de um link na página
+type pdfLink struct {
+	Rect   [4]float64
+	Target string
+}
+
+// cover acrescenta a capa: a imagem centralizada na página, embutida sem
+// recompressão (DCTDecode)
+func (w *pdfWriter) cover(data []byte) error {
+	cfg, format, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return fmt.Errorf("erro ao ler a capa %s: %v", coverImage, err)
+	}
+	if format != "jpeg" {
+		return fmt.Errorf("a capa %s precisa ser JPEG, não %s", coverImage, format)
+	}
+	switch cfg.ColorModel {
+	case color.GrayModel:
+		w.coverSpace = "/DeviceGray"
+	case color.CMYKModel:
+		w.coverSpace = "/DeviceCMYK"
+	default:
+		w.coverSpace = "/DeviceRGB"
+	}
+	w.coverData = data
+	w.coverSize = image.Pt(cfg.Width, cfg.Height)
+
+	w.first = 1
+	w.newPage()
+	scale := min(pageWidth/float64(cfg.Width), pageHeight/float64(cfg.Height))
+	width, height := float64(cfg.Width)*scale, float64(cfg.Height)*scale
+	fmt.Fprintf(&w.page.content, "q %s 0 0 %s %s %s cm /Cover Do Q\n",
+		num(width), num(height), num((pageWidth-width)/2), num((pageHeight-height)/2))
+	return nil
+}
+
+// newPage abre uma página, já com o número no rodapé
+func (w *pdfWriter) newPage() {
+	w.page = &pdfPage{}
+	w.pages = append(w.pages, w.page)
+	w.y = pageHeight - marginTop
+
+	if n := len(w.pages) - w.first; n > 0 {
+		label := strconv.Itoa(n)
+		x := (pageWidth - textWidth(fontRegular, label, footerSize)) / 2
+		w.text(pdfRun{Font: fontRegular, Size: footerSize, Text: []byte(label)}, x, footerY, mutedColor)
+	}
+}
+
+// atTop informa se nada foi escrito na página depois do topo
+func (w *pdfWriter) atTop() bool {
+	return w.y >= pageHeight-marginTop
+}
+
+// gap deixa h pontos em branco, exceto no topo da página
+func (w *pdfWriter) gap(h float64) {
+	if !w.atTop() {
+		w.y -= h
+	}
+}
+
+// ensure muda de página se não couberem h pontos na atual
+func (w *pdfWriter) ensure(h float64) {
+	if w.y-h < marginBottom && !w.atTop() {
+		w.newPage()
+	}
+}
+
+// block escreve um bloco, com o espaço antes dele
+func (w *pdfWriter) block(bl block) {
+	if (bl.Kind == paragraphBlock || bl.Kind == summaryBlock) && plainText(bl.Text) == "" {
+		return // só tags HTML, como a capa no início do sumário
+	}
+	space := 6.0
+	if bl.Kind == itemBlock && w.prev == itemBlock {
+		space = 2
+	}
+	w.prev = bl.Kind
+
+	indent := listIndent * float64(bl.Depth)
+	switch bl.Kind {
+	case headingBlock:
+		w.heading(bl.Level, bl.Text, bl.Anchor)
+	case paragraphBlock:
+		w.gap(space)
+		w.paragraph(bl.Text, textStyle{Size: bodySize}, indent)
+	case summaryBlock:
+		w.gap(space)
+		w.paragraph(bl.Text, textStyle{Size: bodySize, Bold: true}, indent)
+	case itemBlock:
+		w.gap(space)
+		w.item(bl.Marker, bl.Text, bl.Depth)
+	case codeBlock:
+		w.gap(space)
+		w.code(bl.Lines, indent)
+	case tableBlock:
+		w.gap(space)
+		w.table(bl.Rows, indent)
+	case quoteBlock:
+		w.gap(space)
+		w.quote(bl.Text, indent)
+	case ruleBlock:
+		w.gap(space)
+		w.rule()
+	}
+}
+
+// heading escreve o heading e registra a âncora como destino. Partes e
+// capítulos abrem página; os demais não ficam sozinhos no pé da página.
+func (w *pdfWriter) heading(level int, text, anchor string) {
+	size := headingSizes[level]
+	if w.breakAt[anchor] && !w.atTop() {
+		w.newPage()
+	}
+	if w.breakAt[anchor] && level == partLevel {
+		w.y = pageHeight * 0.6 // a parte abre uma página só para ela
+	}
+	w.gap(size * 0.9)
+
+	leading := size * 1.25
+	lines := breakLines(words(parseInline(text), textStyle{Size: size, Bold: true}), contentWidth)
+	w.ensure(float64(len(lines))*leading + 3*bodySize*bodyLeading)
+	if _, ok := w.dests[anchor]; !ok {
+		w.dests[anchor] = pdfDest{Page: len(w.pages) - 1, Y: w.y}
+	}
+	for _, l := range lines {
+		w.drawLine(l, marginX, w.y-size, headingColor)
+		w.y -= leading
+	}
+
+	if level <= chapterLevel {
+		w.y -= 2
+		w.hline(marginX, pageWidth-marginX, w.y)
+		w.y -= 4
+	}
+	w.y -= size * 0.3
+}
+
+// paragraph escreve texto corrido com o recuo indent
+func (w *pdfWriter) paragraph(text string, style textStyle, indent float64) {
+	lines := breakLines(words(parseInline(text), style), contentWidth-indent)
+	w.lines(lines, style.Size, marginX+indent)
+}
+
+// lines escreve as linhas a partir de x, mudando de página quando preciso
+func (w *pdfWriter) lines(lines []pdfLine, size, x float64) {
+	leading := size * bodyLeading
+	for _, l := range lines {
+		w.ensure(leading)
+		w.drawLine(l, x, w.y-size, textColor)
+		w.y -= leading
+	}
+}
+
+// item escreve um item de lista com o marcador à esquerda do texto
+func (w *pdfWriter) item(marker, text string, depth int) {
+	style := textStyle{Size: bodySize}
+	indent := listIndent * float64(depth+1)
+	lines := breakLines(words(parseInline(text), style), contentWidth-indent)
+
+	w.ensure(bodySize * bodyLeading)
+	m := pdfLine{Words: words([]span{{Text: marker}}, style)}
+	for _, word := range m.Words {
+		m.Width += word.Width
+	}
+	w.drawLine(m, marginX+indent-5-m.Width, w.y-bodySize, textColor)
+	w.lines(lines, bodySize, marginX+indent)
+}
+
+// code escreve um bloco de código em monoespaçada sobre fundo cinza.
+// Linhas largas demais continuam na linha de baixo, recuadas, e o fundo é
+// desenhado por página quando o bloco se divide.
+func (w *pdfWriter) code(lines []string, indent float64) {
+	if len(lines) == 0 {
+		return
+	}
+	x, width := marginX+indent, contentWidth-indent
+	var rows []pdfLine
+	for _, l := range lines {
+		word := words([]span{{Text: strings.ReplaceAll(l, "\t", "    "), Code: true}}, textStyle{Size: codeSize})
+		if len(word) == 0 {
+			rows = append(rows, pdfLine{})
+			continue
+		}
+		word[0].Runs[0].Code = false // o fundo é o do bloco
+		parts := splitWord(word[0], width-2*codePadding)
+		rows = append(rows, pdfLine{Words: parts[:1]})
+		if len(parts) > 1 {
+			var rest pdfWord
+			for _, part := range parts[1:] {
+				for _, r := range part.Runs {
+					rest.add(r)
+				}
+			}
+			for _, part := range splitWord(rest, width-2*codePadding-codeWrap) {
+				rows = append(rows, pdfLine{Words: []pdfWord{part}, Indent: codeWrap})
+			}
+		}
+	}
+
+	leading := codeSize * codeLeading
+	for len(rows) > 0 {
+		w.ensure(leading + 2*codePadding)
+		n := int((w.y - marginBottom - 2*codePadding) / leading)
+		n = min(max(n, 1), len(rows))
+		height := float64(n)*leading + 2*codePadding
+		fmt.Fprintf(&w.page.content, "%s rg %s %s %s %s re f\n", codeBackground, num(x), num(w.y-height), num(width), num(height))
+
+		y := w.y - codePadding
+		for _, row := range rows[:n] {
+			w.drawLine(row, x+codePadding+row.Indent, y-codeSize, textColor)
+			y -= leading
+		}
+		w.y -= height
+		rows = rows[n:]
+	}
+}
+
+// table escreve uma tabela com bordas. O cabeçalho se repete quando a
+// tabela continua na página seguinte.
+func (w *pdfWriter) table(rows [][]string, indent float64) {
+	cols := 0
+	for _, row := range rows {
+		cols = max(cols, len(row))
+	}
+
+	// palavras de cada célula e larguras natural e mínima das colunas
+	cells := make([][][]pdfWord, len(rows))
+	natural, minimum := make([]float64, cols), make([]float64, cols)
+	for i, row := range rows {
+		style := textStyle{Size: tableSize, Bold: i == 0}
+		cells[i] = make([][]pdfWord, cols)
+		for j := range cols {
+			if j >= len(row) {
+				continue
+			}
+			ws := words(parseInline(row[j]), style)
+			total := 0.0
+			for k, word := range ws {
+				if k > 0 {
+					total += word.Space
+				}
+				total += word.Width
+				minimum[j] = max(minimum[j], word.Width)
+			}
+			natural[j] = max(natural[j], total)
+			cells[i][j] = ws
+		}
+	}
+	widths := columnWidths(natural, minimum, contentWidth-indent-float64(cols)*2*cellPadding)
+
+	leading := tableSize * bodyLeading
+	layout := func(i int) ([][]pdfLine, float64) {
+		lines := make([][]pdfLine, cols)
+		n := 1
+		for j := range cols {
+			lines[j] = breakLines(cells[i][j], widths[j])
+			n = max(n, len(lines[j]))
+		}
+		return lines, float64(n)*leading + 2*cellPadding
+	}
+	draw := func(i int, lines [][]pdfLine, height float64) {
+		x := marginX + indent
+		c := &w.page.content
+		for j := range cols {
+			width := widths[j] + 2*cellPadding
+			if i == 0 {
+				fmt.Fprintf(c, "%s rg %s %s %s %s re f\n", headerBackground, num(x), num(w.y-height), num(width), num(height))
+			}
+			fmt.Fprintf(c, "%s RG 0.5 w %s %s %s %s re S\n", ruleColor, num(x), num(w.y-height), num(width), num(height))
+			y := w.y - cellPadding
+			for _, l := range lines[j] {
+				w.drawLine(l, x+cellPadding, y-tableSize, textColor)
+				y -= leading
+			}
+			x += width
+		}
+		w.y -= height
+	}
+
+	header, headerHeight := layout(0)
+	for i := range rows {
+		lines, height := header, headerHeight
+		if i > 0 {
+			lines, height = layout(i)
+		}
+		switch {
+		case i == 0 && len(rows) > 1:
+			_, first := layout(1)
+			w.ensure(height + first)
+		case i > 0 && w.y-height < marginBottom:
+			w.newPage()
+			draw(0, header, headerHeight)
+		default:
+			w.ensure(height)
+		}
+		draw(i, lines, height)
+	}
+}
+
+// columnWidths divide a largura entre as colunas: a natural, se couber;
+// senão a mínima (a maior palavra) mais a sobra, na proporção do que cada
+// coluna perderia. Se nem as mínimas couberem, todas encolhem e as
+// palavras são cortadas.
+func columnWidths(natural, minimum []float64, avail float64) []float64 {
+	sumNatural, sumMinimum := 0.0, 0.0
+	for j := range natural {
+		sumNatural += natural[j]
+		sumMinimum += minimum[j]
+	}
+
+	widths := make([]float64, len(natural))
+	for j := range widths {
+		switch {
+		case sumNatural <= avail:
+			widths[j] = natural[j]
+		case sumMinimum >= avail:
+			widths[j] = avail * minimum[j] / sumMinimum
+		default:
+			widths[j] = minimum[j] + (avail-sumMinimum)*(natural[j]-minimum[j])/(sumNatural-sumMinimum)
+		}
+	}
+	return widths
+}
+
+// quote escreve uma citação em itálico, com um filete à esquerda
+func (w *pdfWriter) quote(text string, indent float64) {
+	lines := breakLines(words(parseInline(text), textStyle{Size: bodySize, Italic: true}), contentWidth-indent-12)
+	leading := bodySize * bodyLeading
+	for _, l := range lines {
+		w.ensure(leading)
+		fmt.Fprintf(&w.page.content, "%s rg %s %s 2.5 %s re f\n", ruleColor, num(marginX+indent), num(w.y-leading), num(leading))
+		w.drawLine(l, marginX+indent+12, w.y-bodySize, mutedColor)
+		w.y -= leading
+	}
+}
+
+// rule escreve uma linha horizontal (---)
+func (w *pdfWriter) rule() {
+	w.ensure(12)
+	w.y -= 6
+	w.hline(marginX, pageWidth-marginX, w.y)
+	w.y -= 6
+}
+
+// hline traça uma linha horizontal fina
+func (w *pdfWriter) hline(x0, x1, y float64) {
+	fmt.Fprintf(&w.page.content, "%s RG 0.6 w %s %s m %s %s l S\n", ruleColor, num(x0), num(y), num(x1), num(y))
+}
+
+// drawLine escreve a linha com início em x e linha de base em y: primeiro
+// os fundos do código inline, depois o texto, registrando os links
+func (w *pdfWriter) drawLine(line pdfLine, x, y float64, color string) {
+	xx := x
+	for _, word := range line.Words {
+		xx += word.Space
+		for _, r := range word.Runs {
+			if r.Code {
+				fmt.Fprintf(&w.page.content, "%s rg %s %s %s %s re f\n",
+					codeBackground, num(xx-1), num(y-r.Size*0.25), num(r.Width+2), num(r.Size*1.1))
+			}
+			xx += r.Width
+		}
+	}
+
+	xx = x
+	for _, word := range line.Words {
+		xx += word.Space
+		for _, r := range word.Runs {
+			c := color
+			switch {
+			case r.Link != "":
+				c = linkColor
+				w.page.links = append(w.page.links, pdfLink{
+					Rect:   [4]float64{xx, y - r.Size*0.25, xx + r.Width, y + r.Size*0.9},
+					Target: r.Link,
+				})
+			case r.Code:
+				c = codeColor
+			}
+			w.text(r, xx, y, c)
+			xx += r.Width
+		}
+	}
+}
+
+// text escreve um trecho na posição dada
+func (w *pdfWriter) text(r pdfRun, x, y float64, color string) {
+	fmt.Fprintf(&w.page.content, "BT /F%d %s Tf %s rg 1 0 0 1 %s %s Tm %s Tj ET\n",
+		r.Font+1, num(r.Size), color, num(x), num(y), pdfString(r.Text))
+}
+
+// outlineItem é uma entrada do sumário do leitor de PDF
+type outlineItem struct {
+	Title    string
+	Dest     pdfDest
+	Children []*outlineItem
+}
+
+// outline monta o sumário do leitor com as partes, capítulos e seções
+// que chegaram ao PDF
+func (b *book) outline(dests map[string]pdfDest) []*outlineItem {
+	var walk func(nodes []*node) []*outlineItem
+	walk = func(nodes []*node) []*outlineItem {
+		var items []*outlineItem
+		for _, n := range nodes {
+			anchor := b.anchors[n]
+			dest, ok := dests[anchor]
+			if n.Kind == topicNode || anchor == "" || !ok {
+				continue
+			}
+			items = append(items, &outlineItem{Title: plainText(n.Title), Dest: dest, Children: walk(n.Children)})
+		}
+		return items
+	}
+	return walk(b.Nodes)
+}
+
+// write grava o PDF: catálogo, páginas, fontes, capa e sumário do leitor.
+// Nada depende da hora ou da ordem de mapas, então o mesmo livro gera
+// sempre os mesmos bytes.
+func (w *pdfWriter) write(out io.Writer, outline []*outlineItem, title string) error {
+	var f pdfFile
+	catalog, pages, resources := f.alloc(), f.alloc(), f.alloc()
+
+	fonts := make([]string, len(fontNames))
+	for i, name := range fontNames {
+		encoding := " /Encoding /WinAnsiEncoding"
+		if pdfFont(i) == fontDingbats || pdfFont(i) == fontSymbol {
+			encoding = ""
+		}
+		fonts[i] = fmt.Sprintf("/F%d %d 0 R", i+1, f.add("<< /Type /Font /Subtype /Type1 /BaseFont /%s%s >>", name, encoding))
+	}
+	xobjects := ""
+	if w.coverData != nil {
+		cover := f.alloc()
+		f.stream(cover, fmt.Sprintf("/Type /XObject /Subtype /Image /Width %d /Height %d /ColorSpace %s /BitsPerComponent 8 /Filter /DCTDecode",
+			w.coverSize.X, w.coverSize.Y, w.coverSpace), w.coverData)
+		xobjects = fmt.Sprintf(" /XObject << /Cover %d 0 R >>", cover)
+	}
+	f.set(resources, "<< /ProcSet [/PDF /Text /ImageC] /Font << %s >>%s >>", strings.Join(fonts, " "), xobjects)
+
+	pageObjs := make([]int, len(w.pages))
+	kids := make([]string, len(w.pages))
+	for i := range w.pages {
+		pageObjs[i] = f.alloc()
+		kids[i] = fmt.Sprintf("%d 0 R", pageObjs[i])
+	}
+	for i, p := range w.pages {
+		contents := f.alloc()
+		f.stream(contents, "/Filter /FlateDecode", deflate(p.content.Bytes()))
+
+		var annots []string
+		for _, l := range p.links {
+			action := w.linkAction(l.Target, pageObjs)
+			if action == "" {
+				continue
+			}
+			annots = append(annots, fmt.Sprintf("<< /Type /Annot /Subtype /Link /Rect [%s %s %s %s] /Border [0 0 0] %s >>",
+				num(l.Rect[0]), num(l.Rect[1]), num(l.Rect[2]), num(l.Rect[3]), action))
+		}
+		extra := ""
+		if len(annots) > 0 {
+			extra = " /Annots [" + strings.Join(annots, " ") + "]"
+		}
+		f.set(pageObjs[i], "<< /Type /Page /Parent %d 0 R /MediaBox [0 0 %s %s] /Resources %d 0 R /Contents %d 0 R%s >>",
+			pages, num(pageWidth), num(pageHeight), resources, contents, extra)
+	}
+	f.set(pages, "<< /Type /Pages /Kids [%s] /Count %d >>", strings.Join(kids, " "), len(w.pages))
+
+	extra := ""
+	if len(outline) > 0 {
+		root := f.alloc()
+		first, last, count := w.outlineItems(&f, outline, root, pageObjs, 0)
+		f.set(root, "<< /Type /Outlines /First %d 0 R /Last %d 0 R /Count %d >>", first, last, count)
+		extra += fmt.Sprintf(" /Outlines %d 0 R /PageMode /UseOutlines", root)
+	}
+	if w.first == 1 {
+		extra += " /PageLabels << /Nums [0 << /P (Capa) >> 1 << /S /D >>] >>"
+	}
+	f.set(catalog, "<< /Type /Catalog /Pages %d 0 R%s >>", pages, extra)
+	info := f.add("<< /Title %s /Producer (merge-all) >>", pdfTextString(title))
+	return f.writeTo(out, catalog, info)
+}
+
+// linkAction é a ação do link: destino interno para as âncoras do livro
+// ou URI para o restante. Âncoras que não existem no PDF não viram link.
+func (w *pdfWriter) linkAction(target string, pageObjs []int) string {
+	anchor, internal := strings.CutPrefix(target, "#")
+	if !internal {
+		return "/A << /S /URI /URI " + pdfString([]byte(target)) + " >>"
+	}
+	if unescaped, err := url.PathUnescape(anchor); err == nil {
+		anchor = unescaped
+	}
+	dest, ok := w.dests[anchor]
+	if !ok {
+		return ""
+	}
+	return "/Dest " + destArray(dest, pageObjs)
+}
+
+// outlineItems grava os irmãos do sumário do leitor e retorna o primeiro,
+// o último e quantos itens ficam visíveis. As partes ficam abertas e os
+// capítulos fechados.
+func (w *pdfWriter) outlineItems(f *pdfFile, items []*outlineItem, parent int, pageObjs []int, depth int) (first, last, visible int) {
+	objs := make([]int, len(items))
+	for i := range items {
+		objs[i] = f.alloc()
+	}
+	for i, it := range items {
+		dict := fmt.Sprintf("/Title %s /Parent %d 0 R /Dest %s", pdfTextString(it.Title), parent, destArray(it.Dest, pageObjs))
+		if i > 0 {
+			dict += fmt.Sprintf(" /Prev %d 0 R", objs[i-1])
+		}
+		if i < len(items)-1 {
+			dict += fmt.Sprintf(" /Next %d 0 R", objs[i+1])
+		}
+		visible++
+		if len(it.Children) > 0 {
+			cf, cl, cv := w.outlineItems(f, it.Children, objs[i], pageObjs, depth+1)
+			count := -cv
+			if depth == 0 {
+				count = cv
+				visible += cv
+			}
+			dict += fmt.Sprintf(" /First %d 0 R /Last %d 0 R /Count %d", cf, cl, count)
+		}
+		f.set(objs[i], "<< %s >>", dict)
+	}
+	return objs[0], objs[len(objs)-1], visible
+}
+
+// destArray é o destino explícito [página /XYZ esquerda topo zoom]
+func destArray(d pdfDest, pageObjs []int) string {
+	return fmt.Sprintf("[%d 0 R /XYZ 0 %s null]", pageObjs[d.Page], num(d.Y))
+}
+
+// pdfFile guarda os objetos do PDF, numerados a partir de 1
+type pdfFile struct {
+	objs [][]byte
+}
+
+// alloc reserva o número de um objeto, para referências antes do conteúdo
+func (f *pdfFile) alloc() int {
+	f.objs = append(f.objs, nil)
+	return len(f.objs)
+}
+
+// set define o conteúdo do objeto n
+func (f *pdfFile) set(n int, format string, args ...any) {
+	f.objs[n-1] = fmt.Appendf(nil, format, args...)
+}
+
+// add cria um objeto e retorna o número dele
+func (f *pdfFile) add(format string, args ...any) int {
+	n := f.alloc()
+	f.set(n, format, args...)
+	return n
+}
+
+// stream define o objeto n como um stream com o dicionário dict
+func (f *pdfFile) stream(n int, dict string, data []byte) {
+	obj := fmt.Appendf(nil, "<< %s /Length %d >>\nstream\n", dict, len(data))
+	obj = append(obj, data...)
+	f.objs[n-1] = append(obj, "\nendstream"...)
+}
+
+// writeTo grava o cabeçalho, os objetos, a tabela xref e o trailer
+func (f *pdfFile) writeTo(w io.Writer, root, info int) error {
+	var buf bytes.Buffer
+	buf.WriteString("%PDF-1.4\n%\xe2\xe3\xcf\xd3\n")
+	offsets := make([]int, len(f.objs))
+	for i, obj := range f.objs {
+		offsets[i] = buf.Len()
+		fmt.Fprintf(&buf, "%d 0 obj\n", i+1)
+		buf.Write(obj)
+		buf.WriteString("\nendobj\n")
+	}
+
+	xref := buf.Len()
+	fmt.Fprintf(&buf, "xref\n0 %d\n0000000000 65535 f \n", len(f.objs)+1)
+	for _, off := range offsets {
+		fmt.Fprintf(&buf, "%010d 00000 n \n", off)
+	}
+	fmt.Fprintf(&buf, "trailer\n<< /Size %d /Root %d 0 R /Info %d 0 R >>\nstartxref\n%d\n%%%%EOF\n",
+		len(f.objs)+1, root, info, xref)
+	_, err := buf.WriteTo(w)
+	return err
+}
+
+// deflate comprime um stream de conteúdo (FlateDecode)
+func deflate(data []byte) []byte {
+	var buf bytes.Buffer
+	zw, _ := zlib.NewWriterLevel(&buf, zlib.BestCompression)
+	zw.Write(data)
+	zw.Close()
+	return buf.Bytes()
+}
+
+// pdfString escreve bytes como string literal do PDF
+func pdfString(b []byte) string {
+	var s strings.Builder
+	s.WriteByte('(')
+	for _, c := range b {
+		switch c {
+		case '(', ')', '\\':
+			s.WriteByte('\\')
+			s.WriteByte(c)
+		case '\r':
+			s.WriteString(`\r`)
+		default:
+			s.WriteByte(c)
+		}
+	}
+	s.WriteByte(')')
+	return s.String()
+}
+
+// pdfTextString escreve texto Unicode em UTF-16BE, para títulos e metadados
+func pdfTextString(text string) string {
+	var s strings.Builder
+	s.WriteString("<FEFF")
+	for _, u := range utf16.Encode([]rune(text)) {
+		fmt.Fprintf(&s, "%04X", u)
+	}
+	s.WriteByte('>')
+	return s.String()
+}
+
+// num formata um número com até duas casas, sem zeros à direita
+func num(v float64) string {
+	s := strconv.FormatFloat(v, 'f', 2, 64)
+	s = strings.TrimRight(strings.TrimRight(s, "0"), ".")
+	if s == "-0" || s == "" {
+		return "0"
+	}
+	return s
+}
+
+// helveticaWidths são as larguras da Helvetica (e da Oblique) em WinAnsi,
+// a partir do código 32, em milésimos do corpo
+var helveticaWidths = [224]uint16{
+	278, 278, 355, 556, 556, 889, 667, 191, 333, 333, 389, 584, 278, 333, 278, 278, // 32
+	556, 556, 556, 556, 556, 556, 556, 556, 556, 556, 278, 278, 584, 584, 584, 556, // 48
+	1015, 667, 667, 722, 722, 667, 611, 778, 722, 278, 500, 667, 556, 833, 722, 778, // 64
+	667, 778, 722, 667, 611, 722, 667, 944, 667, 667, 611, 278, 278, 278, 469, 556, // 80
+	333, 556, 556, 500, 556, 556, 278, 556, 556, 222, 222, 500, 222, 833, 556, 556, // 96
+	556, 556, 333, 500, 278, 556, 500, 722, 500, 500, 500, 334, 260, 334, 584, 0, // 112
+	556, 0, 222, 556, 333, 1000, 556, 556, 333, 1000, 667, 333, 1000, 0, 611, 0, // 128
+	0, 222, 222, 333, 333, 350, 556, 1000, 333, 1000, 500, 333, 944, 0, 500, 667, // 144
+	278, 333, 556, 556, 556, 556, 260, 556, 333, 737, 370, 556, 584, 333, 737, 333, // 160
+	400, 584, 333, 333, 333, 556, 537, 278, 333, 333, 365, 556, 834, 834, 834, 611, // 176
+	667, 667, 667, 667, 667, 667, 1000, 722, 667, 667, 667, 667, 278, 278, 278, 278, // 192
+	722, 722, 778, 778, 778, 778, 778, 584, 778, 722, 722, 722, 722, 667, 667, 611, // 208
+	556, 556, 556, 556, 556, 556, 889, 500, 556, 556, 556, 556, 278, 278, 278, 278, // 224
+	556, 556, 556, 556, 556, 556, 556, 584, 611, 556, 556, 556, 556, 500, 556, 500, // 240
+}
+
+// helveticaBoldWidths são as larguras da Helvetica-Bold (e da
+// BoldOblique) em WinAnsi, a partir do código 32
+var helveticaBoldWidths = [224]uint16{
+	278, 333, 474, 556, 556, 889, 722, 238, 333, 333, 389, 584, 278, 333, 278, 278, // 32
+	556, 556, 556, 556, 556, 556, 556, 556, 556, 556, 333, 333, 584, 584, 584, 611, // 48
+	975, 722, 722, 722, 722, 667, 611, 778, 722, 278, 556, 722, 611, 833, 722, 778, // 64
+	667, 778, 722, 667, 611, 722, 667, 944, 667, 667, 611, 333, 278, 333, 584, 556, // 80
+	333, 556, 611, 556, 611, 556, 333, 611, 611, 278, 278, 556, 278, 889, 611, 611, // 96
+	611, 611, 389, 556, 333, 611, 556, 778, 556, 556, 500, 389, 280, 389, 584, 0, // 112
+	556, 0, 278, 556, 500, 1000, 556, 556, 333, 1000, 667, 333, 1000, 0, 611, 0, // 128
+	0, 278, 278, 500, 500, 350, 556, 1000, 333, 1000, 556, 333, 944, 0, 500, 667, // 144
+	278, 333, 556, 556, 556, 556, 280, 556, 333, 737, 370, 556, 584, 333, 737, 333, // 160
+	400, 584, 333, 333, 333, 611, 556, 278, 333, 333, 365, 556, 834, 834, 834, 611, // 176
+	722, 722, 722, 722, 722, 722, 1000, 722, 667, 667, 667, 667, 278, 278, 278, 278, // 192
+	722, 722, 778, 778, 778, 778, 778, 584, 778, 722, 722, 722, 722, 667, 667, 611, // 208
+	556, 556, 556, 556, 556, 556, 889, 556, 556, 556, 556, 556, 278, 278, 278, 278, // 224
+	611, 611, 611, 611, 611, 611, 611, 584, 611, 611, 611, 611, 611, 556, 611, 556, // 240
+}
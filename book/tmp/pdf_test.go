@@ -0,0 +1,400 @@
+//go:build ignore
+
+// Testes da saída em PDF. Rode junto com o merge-all e o pdf.go, a partir
+// de book/:
+//
+//	go test tmp/merge-all.go tmp/pdf.go tmp/pdf_test.go
+//
+// O PDF do livro de teste (testdata/livro) é lido de volta pela tabela
+// xref, como um leitor faria: páginas, sumário do leitor e links.
+
+package main
+
+import (
+	"bytes"
+	"compress/zlib"
+	"image"
+	"image/jpeg"
+	"io"
+	"maps"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"testing"
+	"unicode/utf16"
+)
+
+// pdfTestSummary é o sumário do livro de teste
+const pdfTestSummary = "testdata/livro/livro.md"
+
+// Expressões usadas para ler o PDF gerado
+var (
+	startxrefRegex = regexp.MustCompile(`startxref\n(\d+)\n%%EOF\n$`)
+	xrefRegex      = regexp.MustCompile(`^xref\n0 (\d+)\n`)
+	trailerRegex   = regexp.MustCompile(`^trailer\n<< /Size (\d+) /Root (\d+) 0 R /Info (\d+) 0 R >>\n`)
+	refRegex       = regexp.MustCompile(`(\d+) 0 R`)
+	lengthRegex    = regexp.MustCompile(`/Length (\d+)`)
+	destRegex      = regexp.MustCompile(`/Dest \[(\d+) 0 R /XYZ 0 [\d.]+ null\]`)
+	uriRegex       = regexp.MustCompile(`/URI \(([^)]*)\)`)
+	titleRegex     = regexp.MustCompile(`/Title <FEFF([0-9A-F]*)>`)
+)
+
+// pdfDoc é um PDF lido pela tabela xref: o dicionário (ou dicionário e
+// stream) de cada objeto e o catálogo
+type pdfDoc struct {
+	objs map[int][]byte
+	root int
+}
+
+// readPDF confere a estrutura do arquivo (cabeçalho, deslocamentos da
+// xref, tamanho dos streams, trailer e referências) e devolve os objetos
+func readPDF(t *testing.T, data []byte) *pdfDoc {
+	t.Helper()
+	if !bytes.HasPrefix(data, []byte("%PDF-1.4\n")) {
+		t.Fatalf("cabeçalho inválido: %q", data[:min(len(data), 16)])
+	}
+	m := startxrefRegex.FindSubmatch(data)
+	if m == nil {
+		t.Fatal("startxref ausente no fim do arquivo")
+	}
+	xref, _ := strconv.Atoi(string(m[1]))
+	if xref >= len(data) {
+		t.Fatalf("startxref %d além do fim do arquivo", xref)
+	}
+	m = xrefRegex.FindSubmatch(data[xref:])
+	if m == nil {
+		t.Fatalf("startxref %d não aponta para a tabela xref", xref)
+	}
+	size, _ := strconv.Atoi(string(m[1]))
+	entries := data[xref+len(m[0]):]
+	if len(entries) < 20*size || string(entries[:20]) != "0000000000 65535 f \n" {
+		t.Fatal("tabela xref incompleta")
+	}
+
+	doc := &pdfDoc{objs: make(map[int][]byte)}
+	for i := 1; i < size; i++ {
+		entry := string(entries[20*i : 20*i+20])
+		if !strings.HasSuffix(entry, " 00000 n \n") {
+			t.Fatalf("entrada %d da xref inválida: %q", i, entry)
+		}
+		off, _ := strconv.Atoi(entry[:10])
+		header := strconv.Itoa(i) + " 0 obj\n"
+		if !bytes.HasPrefix(data[off:], []byte(header)) {
+			t.Fatalf("a xref aponta o objeto %d para %d, onde está %q", i, off, data[off:min(len(data), off+16)])
+		}
+		end := bytes.Index(data[off:], []byte("\nendobj\n"))
+		if end < 0 {
+			t.Fatalf("objeto %d sem endobj", i)
+		}
+		obj := data[off+len(header) : off+end]
+		if dict, stream, ok := bytes.Cut(obj, []byte(" >>\nstream\n")); ok {
+			n, _ := strconv.Atoi(string(lengthRegex.FindSubmatch(dict)[1]))
+			if len(stream) != n+len("\nendstream") || !bytes.HasSuffix(stream, []byte("\nendstream")) {
+				t.Fatalf("objeto %d: /Length %d não confere com o stream", i, n)
+			}
+		}
+		doc.objs[i] = obj
+	}
+
+	m = trailerRegex.FindSubmatch(entries[20*size:])
+	if m == nil {
+		t.Fatalf("trailer inválido: %q", entries[20*size:])
+	}
+	if n, _ := strconv.Atoi(string(m[1])); n != size {
+		t.Errorf("trailer com /Size %d, xref com %d", n, size)
+	}
+	doc.root, _ = strconv.Atoi(string(m[2]))
+
+	for i, obj := range doc.objs {
+		for _, ref := range refRegex.FindAllSubmatch(doc.dict(obj), -1) {
+			if n, _ := strconv.Atoi(string(ref[1])); doc.objs[n] == nil {
+				t.Errorf("objeto %d referencia o objeto %s, que não existe", i, ref[1])
+			}
+		}
+	}
+	return doc
+}
+
+// dict retorna o objeto sem o stream
+func (doc *pdfDoc) dict(obj []byte) []byte {
+	dict, _, _ := bytes.Cut(obj, []byte("\nstream\n"))
+	return dict
+}
+
+// ref lê a referência key (como /Pages) do objeto n, ou 0
+func (doc *pdfDoc) ref(n int, key string) int {
+	m := regexp.MustCompile(regexp.QuoteMeta(key) + ` (\d+) 0 R`).FindSubmatch(doc.dict(doc.objs[n]))
+	if m == nil {
+		return 0
+	}
+	v, _ := strconv.Atoi(string(m[1]))
+	return v
+}
+
+// pages retorna os objetos das páginas, na ordem de /Kids
+func (doc *pdfDoc) pages(t *testing.T) []int {
+	t.Helper()
+	tree := doc.objs[doc.ref(doc.root, "/Pages")]
+	kids, _, _ := bytes.Cut(tree[bytes.Index(tree, []byte("/Kids ["))+len("/Kids ["):], []byte("]"))
+	var pages []int
+	for _, ref := range refRegex.FindAllSubmatch(kids, -1) {
+		n, _ := strconv.Atoi(string(ref[1]))
+		if !bytes.Contains(doc.objs[n], []byte("/Type /Page ")) {
+			t.Errorf("/Kids inclui o objeto %d, que não é página", n)
+		}
+		pages = append(pages, n)
+	}
+	if !bytes.Contains(tree, []byte("/Count "+strconv.Itoa(len(pages))+" ")) {
+		t.Errorf("/Count da árvore de páginas difere das %d páginas em /Kids", len(pages))
+	}
+	return pages
+}
+
+// content retorna o conteúdo da página já descomprimido
+func (doc *pdfDoc) content(t *testing.T, page int) string {
+	t.Helper()
+	_, stream, _ := bytes.Cut(doc.objs[doc.ref(page, "/Contents")], []byte("\nstream\n"))
+	zr, err := zlib.NewReader(bytes.NewReader(bytes.TrimSuffix(stream, []byte("\nendstream"))))
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, err := io.ReadAll(zr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(data)
+}
+
+// pdfOutline é uma entrada do sumário do leitor lida de volta
+type pdfOutline struct {
+	Title    string
+	Page     int // índice da página
+	Children []pdfOutline
+}
+
+// outline percorre /First e /Next a partir de parent, conferindo /Parent,
+// /Prev e /Last
+func (doc *pdfDoc) outline(t *testing.T, parent int, pages []int) []pdfOutline {
+	t.Helper()
+	var items []pdfOutline
+	prev := 0
+	for n := doc.ref(parent, "/First"); n != 0; n = doc.ref(n, "/Next") {
+		obj := doc.objs[n]
+		if doc.ref(n, "/Parent") != parent || doc.ref(n, "/Prev") != prev {
+			t.Errorf("item %d do sumário com /Parent ou /Prev errado", n)
+		}
+		item := pdfOutline{Title: pdfTitle(obj), Page: -1}
+		if m := destRegex.FindSubmatch(obj); m != nil {
+			p, _ := strconv.Atoi(string(m[1]))
+			item.Page = slices.Index(pages, p)
+		}
+		if item.Page < 0 {
+			t.Errorf("item %q do sumário sem destino em uma página", item.Title)
+		}
+		item.Children = doc.outline(t, n, pages)
+		items = append(items, item)
+		prev = n
+	}
+	if doc.ref(parent, "/Last") != prev {
+		t.Errorf("/Last de %d não é o último item", parent)
+	}
+	return items
+}
+
+// pdfTitle decodifica o /Title em UTF-16BE
+func pdfTitle(obj []byte) string {
+	m := titleRegex.FindSubmatch(obj)
+	if m == nil {
+		return ""
+	}
+	units := make([]uint16, len(m[1])/4)
+	for i := range units {
+		v, _ := strconv.ParseUint(string(m[1][4*i:4*i+4]), 16, 16)
+		units[i] = uint16(v)
+	}
+	return string(utf16.Decode(units))
+}
+
+// buildTestPDF gera o PDF do livro cujo sumário é summary
+func buildTestPDF(t *testing.T, summary string) []byte {
+	t.Helper()
+	b, err := parseSummary(summary)
+	if err != nil {
+		t.Fatal(err)
+	}
+	out := filepath.Join(t.TempDir(), "livro.pdf")
+	if err := b.writePDF(out); err != nil {
+		t.Fatal(err)
+	}
+	data, err := os.ReadFile(out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return data
+}
+
+func TestPDFStructure(t *testing.T) {
+	doc := readPDF(t, buildTestPDF(t, pdfTestSummary))
+	pages := doc.pages(t)
+
+	// sumário, depois uma página por parte e por capítulo: o livro de
+	// teste é curto e nenhuma seção passa de uma página
+	if len(pages) != 6 {
+		t.Errorf("%d páginas, esperadas 6", len(pages))
+	}
+	for i, p := range pages {
+		want := "(" + strconv.Itoa(i+1) + ") Tj"
+		if !strings.Contains(doc.content(t, p), want) {
+			t.Errorf("página %d sem o número no rodapé", i+1)
+		}
+	}
+
+	// o sumário do leitor segue o sumário do livro, sem os tópicos e sem
+	// os emojis dos títulos
+	outline := doc.outline(t, doc.ref(doc.root, "/Outlines"), pages)
+	type entry struct {
+		depth int
+		title string
+		page  int
+	}
+	var got []entry
+	var walk func(items []pdfOutline, depth int)
+	walk = func(items []pdfOutline, depth int) {
+		for _, it := range items {
+			got = append(got, entry{depth, it.Title, it.Page})
+			walk(it.Children, depth+1)
+		}
+	}
+	walk(outline, 0)
+	want := []entry{
+		{0, "Parte 1: Básico", 1},
+		{1, "Capítulo 1: Começo", 2},
+		{2, "1.1 Olá, mundo", 2},
+		{2, "1.2 Variáveis", 2},
+		{1, "Capítulo 2: Ainda por escrever", 3},
+		{2, "2.1 Seção que falta", 3},
+		{0, "Parte 2: Avançado", 4},
+		{1, "Capítulo 3: Links e imagens", 5},
+		{2, "3.1 Referências", 5},
+	}
+	if !slices.Equal(got, want) {
+		t.Errorf("sumário do leitor:\n%v\nesperado:\n%v", got, want)
+	}
+
+	// links internos apontam para páginas do livro; os do sumário
+	// levam às seções, os externos viram URI
+	targets := make(map[int]int)
+	var uris []string
+	for i, p := range pages {
+		for _, m := range destRegex.FindAllSubmatch(doc.objs[p], -1) {
+			n, _ := strconv.Atoi(string(m[1]))
+			if !slices.Contains(pages, n) {
+				t.Errorf("link na página %d aponta para o objeto %d, que não é página", i+1, n)
+			}
+			targets[slices.Index(pages, n)]++
+		}
+		for _, m := range uriRegex.FindAllSubmatch(doc.objs[p], -1) {
+			uris = append(uris, string(m[1]))
+		}
+	}
+	for _, page := range []int{2, 3, 5} {
+		if targets[page] == 0 {
+			t.Errorf("nenhum link leva à página %d", page+1)
+		}
+	}
+	if !slices.Contains(uris, "https://go.dev") {
+		t.Errorf("link externo ausente; URIs: %q", uris)
+	}
+}
+
+func TestPDFIsReproducible(t *testing.T) {
+	first := buildTestPDF(t, pdfTestSummary)
+	second := buildTestPDF(t, pdfTestSummary)
+	if !bytes.Equal(first, second) {
+		t.Error("duas gerações seguidas do PDF diferem")
+	}
+}
+
+func TestPDFCover(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.CopyFS(dir, os.DirFS(filepath.Dir(pdfTestSummary))); err != nil {
+		t.Fatal(err)
+	}
+	f, err := os.Create(filepath.Join(dir, coverImage))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := jpeg.Encode(f, image.NewGray(image.Rect(0, 0, 30, 40)), nil); err != nil {
+		t.Fatal(err)
+	}
+	if err := f.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	doc := readPDF(t, buildTestPDF(t, filepath.Join(dir, filepath.Base(pdfTestSummary))))
+	pages := doc.pages(t)
+	if len(pages) != 7 {
+		t.Errorf("%d páginas, esperadas 7 com a capa", len(pages))
+	}
+	if c := doc.content(t, pages[0]); !strings.Contains(c, "/Cover Do") || strings.Contains(c, "Tj") {
+		t.Errorf("a primeira página não é só a capa: %q", c)
+	}
+	cover := doc.objs[doc.ref(doc.ref(pages[0], "/Resources"), "/Cover")]
+	if !bytes.Contains(cover, []byte("/Width 30 /Height 40 /ColorSpace /DeviceGray")) ||
+		!bytes.Contains(cover, []byte("/Filter /DCTDecode")) {
+		t.Errorf("capa embutida com dicionário inesperado: %q", doc.dict(cover))
+	}
+	if !bytes.Contains(doc.objs[doc.root], []byte("/PageLabels")) {
+		t.Error("catálogo sem /PageLabels para numerar depois da capa")
+	}
+	if !strings.Contains(doc.content(t, pages[1]), "(1) Tj") {
+		t.Error("a numeração não começa em 1 depois da capa")
+	}
+}
+
+// Caracteres fora das fontes padrão somem do PDF: a geração avisa quais
+func TestPDFReportsMissingGlyphs(t *testing.T) {
+	text := "Olá, 言語! ► ▲ 🚀\uFE0F 🚀 café — “aspas” ✔ ✅ ─ x ? ok"
+	want := map[rune]int{'言': 1, '語': 1, '►': 1, '▲': 1, '🚀': 2}
+	if got := missingGlyphs(text); !maps.Equal(got, want) {
+		t.Errorf("missingGlyphs = %v, esperado %v", got, want)
+	}
+
+	dir := t.TempDir()
+	if err := os.CopyFS(dir, os.DirFS(filepath.Dir(pdfTestSummary))); err != nil {
+		t.Fatal(err)
+	}
+	chapter := filepath.Join(dir, "capitulos", "cap1", "ola.md")
+	data, err := os.ReadFile(chapter)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(chapter, append(data, "\nGo em japonês: 言語 ► 🚀\n"...), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	// o aviso sai na saída padrão, como os demais do merge-all
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	stdout := os.Stdout
+	os.Stdout = w
+	defer func() { os.Stdout = stdout }()
+	buildTestPDF(t, filepath.Join(dir, filepath.Base(pdfTestSummary)))
+	os.Stdout = stdout
+	w.Close()
+	out, err := io.ReadAll(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, glyph := range []string{"言 U+8A00 ×1", "語 U+8A9E ×1", "► U+25BA ×1", "🚀 U+1F680 ×1"} {
+		if !strings.Contains(string(out), glyph) {
+			t.Errorf("aviso sem %s:\n%s", glyph, out)
+		}
+	}
+}
@@ -0,0 +1,196 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// assetsDir guarda os anexos (imagens, diagramas) do workspace, com o
+// hash do conteúdo como nome: o mesmo arquivo enviado duas vezes é
+// gravado uma única vez
+const assetsDir = "assets"
+
+// maxAssetBytes limita o tamanho de cada anexo
+const maxAssetBytes = 10 << 20
+
+// assetTypes são os tipos aceitos, detectados pelo conteúdo, e a extensão
+// usada no disco. SVG fica de fora: pode carregar scripts.
+var assetTypes = map[string]string{
+	"image/png":       ".png",
+	"image/jpeg":      ".jpg",
+	"image/gif":       ".gif",
+	"image/webp":      ".webp",
+	"application/pdf": ".pdf",
+}
+
+// assetRef encontra referências a anexos no Markdown das seções
+var assetRef = regexp.MustCompile(`assets/([0-9a-f]{64}\.[a-z]+)`)
+
+// assetName valida o nome pedido em /assets/{name}
+var assetName = regexp.MustCompile(`^[0-9a-f]{64}\.[a-z]+$`)
+
+// ErrInvalidAsset é retornado para arquivos de tipo não permitido
+var ErrInvalidAsset = errors.New("tipo de arquivo não permitido: use PNG, JPEG, GIF, WebP ou PDF")
+
+// Asset descreve um anexo gravado e o trecho de Markdown que o insere
+type Asset struct {
+	Name     string `json:"name"`
+	URL      string `json:"url"`
+	Type     string `json:"type"`
+	Size     int    `json:"size"`
+	Markdown string `json:"markdown"`
+}
+
+// isImage indica se o anexo é exibido inline com ![...]()
+func isImage(name string) bool {
+	return filepath.Ext(name) != ".pdf"
+}
+
+// assetMediaType retorna o tipo MIME a partir da extensão gravada
+func assetMediaType(name string) string {
+	for mediaType, ext := range assetTypes {
+		if ext == filepath.Ext(name) {
+			return mediaType
+		}
+	}
+	return "application/octet-stream"
+}
+
+// SaveAsset grava o anexo em assets/<sha256><ext>. filename é o nome
+// original, usado apenas no texto alternativo do Markdown.
+func (ws *Workspace) SaveAsset(data []byte, filename string) (*Asset, error) {
+	mediaType := http.DetectContentType(data)
+	ext, ok := assetTypes[mediaType]
+	if !ok {
+		return nil, fmt.Errorf("%w (recebido %s)", ErrInvalidAsset, mediaType)
+	}
+
+	name := contentHash(string(data)) + ext
+	path := filepath.Join(ws.Root, assetsDir, name)
+	if _, err := os.Stat(path); errors.Is(err, os.ErrNotExist) {
+		if err := writeFileAtomic(path, data, 0644); err != nil {
+			return nil, fmt.Errorf("erro ao salvar anexo: %v", err)
+		}
+	}
+
+	label := strings.TrimSuffix(filepath.Base(filename), filepath.Ext(filename))
+	label = strings.NewReplacer("[", "", "]", "").Replace(label)
+	url := assetsDir + "/" + name
+	markdown := fmt.Sprintf("[%s](%s)", label, url)
+	if isImage(name) {
+		markdown = "!" + markdown
+	}
+
+	return &Asset{
+		Name:     name,
+		URL:      url,
+		Type:     mediaType,
+		Size:     len(data),
+		Markdown: markdown,
+	}, nil
+}
+
+// referencedAssets lista, sem repetição, os anexos citados pelas seções
+// que existem em assets/
+func (d *Document) referencedAssets() []string {
+	seen := make(map[string]bool)
+	var names []string
+	for _, s := range d.Sections {
+		for _, m := range assetRef.FindAllStringSubmatch(s.Content, -1) {
+			name := m[1]
+			if seen[name] {
+				continue
+			}
+			seen[name] = true
+			if _, err := os.Stat(filepath.Join(d.AssetsDir, name)); err == nil {
+				names = append(names, name)
+			}
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+// copyAssets copia os anexos citados pelas seções para dir/assets, ao
+// lado do arquivo exportado, para que os links relativos funcionem
+func (d *Document) copyAssets(dir string) error {
+	for _, name := range d.referencedAssets() {
+		data, err := os.ReadFile(filepath.Join(d.AssetsDir, name))
+		if err != nil {
+			return err
+		}
+		if err := writeFileAtomic(filepath.Join(dir, assetsDir, name), data, 0644); err != nil {
+			return fmt.Errorf("erro ao copiar anexo %s: %v", name, err)
+		}
+	}
+	return nil
+}
+
+// handleAssets recebe um anexo em POST /api/assets (multipart, campo
+// "file") e devolve o Markdown para inseri-lo na seção
+func handleAssets(ws *Workspace) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			w.Header().Set("Allow", "POST")
+			http.Error(w, "método não permitido", http.StatusMethodNotAllowed)
+			return
+		}
+
+		// folga para os cabeçalhos do multipart
+		r.Body = http.MaxBytesReader(w, r.Body, maxAssetBytes+64<<10)
+		file, header, err := r.FormFile("file")
+		if err != nil {
+			var tooLarge *http.MaxBytesError
+			if errors.As(err, &tooLarge) {
+				http.Error(w, "arquivo muito grande", http.StatusRequestEntityTooLarge)
+				return
+			}
+			http.Error(w, fmt.Sprintf("campo file é obrigatório: %v", err), http.StatusBadRequest)
+			return
+		}
+		defer file.Close()
+
+		data, err := io.ReadAll(io.LimitReader(file, maxAssetBytes+1))
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		if len(data) > maxAssetBytes {
+			http.Error(w, "arquivo muito grande", http.StatusRequestEntityTooLarge)
+			return
+		}
+
+		asset, err := ws.SaveAsset(data, header.Filename)
+		if err != nil {
+			status := http.StatusInternalServerError
+			if errors.Is(err, ErrInvalidAsset) {
+				status = http.StatusUnsupportedMediaType
+			}
+			http.Error(w, err.Error(), status)
+			return
+		}
+		writeJSON(w, http.StatusCreated, asset)
+	}
+}
+
+// handleAsset serve GET /assets/{name}, usado pelo preview do editor
+func handleAsset(ws *Workspace) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		name := r.PathValue("name")
+		if !assetName.MatchString(name) {
+			http.NotFound(w, r)
+			return
+		}
+		w.Header().Set("Content-Type", assetMediaType(name))
+		w.Header().Set("X-Content-Type-Options", "nosniff")
+		w.Header().Set("Cache-Control", "private, max-age=31536000, immutable")
+		http.ServeFile(w, r, filepath.Join(ws.Root, assetsDir, name))
+	}
+}
@@ -0,0 +1,153 @@
+package main
+
+import (
+	"bytes"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"testing"
+)
+
+// upload envia data como o campo "file" de um formulário multipart
+func (c *testClient) upload(filename string, data []byte) *http.Response {
+	c.t.Helper()
+	var body bytes.Buffer
+	mw := multipart.NewWriter(&body)
+	part, err := mw.CreateFormFile("file", filename)
+	if err != nil {
+		c.t.Fatal(err)
+	}
+	part.Write(data)
+	if err := mw.Close(); err != nil {
+		c.t.Fatal(err)
+	}
+
+	req, err := http.NewRequest(http.MethodPost, c.ts.URL+"/api/assets", &body)
+	if err != nil {
+		c.t.Fatal(err)
+	}
+	req.AddCookie(c.cookie)
+	req.Header.Set(csrfHeader, c.csrf)
+	req.Header.Set("Content-Type", mw.FormDataContentType())
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		c.t.Fatal(err)
+	}
+	c.t.Cleanup(func() { resp.Body.Close() })
+	return resp
+}
+
+func TestAssetUpload(t *testing.T) {
+	ts := newTestServer(t)
+	author := ts.login(t, "author")
+	png := testPNG(t)
+
+	resp := author.upload("../../etc/[Diagrama] final.png", png)
+	if resp.StatusCode != http.StatusCreated {
+		t.Fatalf("status %d", resp.StatusCode)
+	}
+	var asset Asset
+	decode(t, resp, &asset)
+
+	// o nome em disco é o hash do conteúdo; o nome enviado só vira o
+	// texto alternativo, sem diretórios nem colchetes
+	if !regexp.MustCompile(`^[0-9a-f]{64}\.png$`).MatchString(asset.Name) {
+		t.Errorf("nome = %q", asset.Name)
+	}
+	if want := "![Diagrama final](assets/" + asset.Name + ")"; asset.Markdown != want {
+		t.Errorf("markdown = %q, esperado %q", asset.Markdown, want)
+	}
+	if asset.URL != "assets/"+asset.Name || asset.Type != "image/png" || asset.Size != len(png) {
+		t.Errorf("anexo = %+v", asset)
+	}
+	entries, err := os.ReadDir(filepath.Join(ts.ws.Root, assetsDir))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 1 || entries[0].Name() != asset.Name {
+		t.Errorf("arquivos em %s: %v", assetsDir, entries)
+	}
+
+	// o mesmo conteúdo com outro nome não grava uma cópia
+	var again Asset
+	decode(t, author.upload("outro.png", png), &again)
+	if again.Name != asset.Name {
+		t.Errorf("mesmo conteúdo com nomes diferentes: %s e %s", asset.Name, again.Name)
+	}
+
+	resp = author.expect(http.StatusOK, http.MethodGet, "/assets/"+asset.Name, nil)
+	data, _ := io.ReadAll(resp.Body)
+	if !bytes.Equal(data, png) {
+		t.Error("anexo servido difere do enviado")
+	}
+	if ct := resp.Header.Get("Content-Type"); ct != "image/png" {
+		t.Errorf("Content-Type = %s", ct)
+	}
+	if nosniff := resp.Header.Get("X-Content-Type-Options"); nosniff != "nosniff" {
+		t.Errorf("X-Content-Type-Options = %q", nosniff)
+	}
+}
+
+func TestAssetUploadRejects(t *testing.T) {
+	ts := newTestServer(t)
+	author := ts.login(t, "author")
+
+	tests := []struct {
+		name     string
+		filename string
+		data     []byte
+		status   int
+	}{
+		// o tipo vem do conteúdo, não da extensão
+		{"HTML com extensão de imagem", "foto.png", []byte("<html><script>alert(1)</script></html>"), http.StatusUnsupportedMediaType},
+		{"SVG", "desenho.svg", []byte(`<svg xmlns="http://www.w3.org/2000/svg"><script>alert(1)</script></svg>`), http.StatusUnsupportedMediaType},
+		{"texto", "notas.txt", []byte("só texto"), http.StatusUnsupportedMediaType},
+		{"acima do limite", "grande.png", append(testPNG(t), make([]byte, maxAssetBytes)...), http.StatusRequestEntityTooLarge},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if resp := author.upload(tt.filename, tt.data); resp.StatusCode != tt.status {
+				t.Errorf("status %d, esperado %d", resp.StatusCode, tt.status)
+			}
+		})
+	}
+
+	author.expect(http.StatusBadRequest, http.MethodPost, "/api/assets", nil)
+	if entries, _ := os.ReadDir(filepath.Join(ts.ws.Root, assetsDir)); len(entries) != 0 {
+		t.Errorf("arquivos recusados foram gravados: %v", entries)
+	}
+	// leitores não enviam anexos
+	if resp := ts.login(t, "reader").upload("foto.png", testPNG(t)); resp.StatusCode != http.StatusForbidden {
+		t.Errorf("reader: status %d, esperado 403", resp.StatusCode)
+	}
+}
+
+func TestAssetRouteRejectsPathTraversal(t *testing.T) {
+	ts := newTestServer(t)
+	reader := ts.login(t, "reader")
+	// um arquivo com nome de anexo fora de assets/
+	name := strings.Repeat("a", 64) + ".png"
+	writeFiles(t, ts.ws.Root, map[string]string{name: "fora de assets"})
+
+	for _, path := range []string{
+		"/assets/" + name,
+		"/assets/..%2F" + name,
+		"/assets/..%2Fusers.json",
+		"/assets/..%5Cusers.json",
+		"/assets/%2E%2E%2Fusers.json",
+		"/assets/users.json",
+	} {
+		resp := reader.do(http.MethodGet, path, nil)
+		body, _ := io.ReadAll(resp.Body)
+		if resp.StatusCode != http.StatusNotFound {
+			t.Errorf("GET %s: status %d, esperado 404", path, resp.StatusCode)
+		}
+		if bytes.Contains(body, []byte("fora de assets")) || bytes.Contains(body, []byte("PasswordHash")) {
+			t.Errorf("GET %s leu arquivo fora de assets/: %s", path, body)
+		}
+	}
+}
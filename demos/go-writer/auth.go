@@ -0,0 +1,315 @@
+package main
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	sessionCookie = "gowriter_session"
+	sessionTTL    = 12 * time.Hour
+	csrfHeader    = "X-CSRF-Token"
+	csrfField     = "csrf" // campo usado pelos formulários HTML
+	auditFile     = "audit.jsonl"
+)
+
+// session é um login ativo. O papel não fica aqui: é lido do cadastro a
+// cada requisição, para que mudanças valham sem novo login.
+type session struct {
+	Username string
+	CSRF     string
+	Expires  time.Time
+}
+
+// Auth cuida do login, das sessões em memória, da proteção CSRF e do
+// registro de auditoria das alterações
+type Auth struct {
+	users *UserStore
+	audit string
+
+	mu       sync.Mutex
+	sessions map[string]*session
+}
+
+// NewAuth cria o controle de acesso do workspace em root
+func NewAuth(users *UserStore, root string) *Auth {
+	return &Auth{
+		users:    users,
+		audit:    filepath.Join(root, auditFile),
+		sessions: make(map[string]*session),
+	}
+}
+
+// randomToken gera um token aleatório de 256 bits
+func randomToken() string {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		panic(err)
+	}
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+// startSession cria a sessão e envia o cookie
+func (a *Auth) startSession(w http.ResponseWriter, r *http.Request, username string) {
+	token := randomToken()
+	s := &session{
+		Username: username,
+		CSRF:     randomToken(),
+		Expires:  time.Now().Add(sessionTTL),
+	}
+
+	a.mu.Lock()
+	a.sessions[token] = s
+	a.mu.Unlock()
+
+	http.SetCookie(w, &http.Cookie{
+		Name:     sessionCookie,
+		Value:    token,
+		Path:     "/",
+		Expires:  s.Expires,
+		HttpOnly: true,
+		Secure:   r.TLS != nil,
+		SameSite: http.SameSiteLaxMode,
+	})
+}
+
+// endSession remove a sessão da requisição e apaga o cookie
+func (a *Auth) endSession(w http.ResponseWriter, r *http.Request) {
+	if c, err := r.Cookie(sessionCookie); err == nil {
+		a.mu.Lock()
+		delete(a.sessions, c.Value)
+		a.mu.Unlock()
+	}
+	http.SetCookie(w, &http.Cookie{
+		Name:     sessionCookie,
+		Value:    "",
+		Path:     "/",
+		MaxAge:   -1,
+		HttpOnly: true,
+	})
+}
+
+// session retorna a sessão válida da requisição, ou nil
+func (a *Auth) session(r *http.Request) *session {
+	c, err := r.Cookie(sessionCookie)
+	if err != nil {
+		return nil
+	}
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	s, ok := a.sessions[c.Value]
+	if !ok {
+		return nil
+	}
+	if time.Now().After(s.Expires) {
+		delete(a.sessions, c.Value)
+		return nil
+	}
+	return s
+}
+
+// currentUser é o usuário autenticado da requisição
+type currentUser struct {
+	User
+	CSRF string
+}
+
+type userKey struct{}
+
+// requestUser retorna o usuário autenticado pelo middleware
+func requestUser(r *http.Request) (currentUser, bool) {
+	u, ok := r.Context().Value(userKey{}).(currentUser)
+	return u, ok
+}
+
+// isPublic indica os caminhos acessíveis sem login
+func isPublic(path string) bool {
+	return path == "/login" || strings.HasPrefix(path, "/static/")
+}
+
+// isSafeMethod indica métodos que não alteram nada
+func isSafeMethod(method string) bool {
+	return method == http.MethodGet || method == http.MethodHead || method == http.MethodOptions
+}
+
+// Middleware exige login em tudo que não for público. Métodos que alteram
+// dados precisam do token CSRF da sessão e ao menos o papel author, e
+// são registrados no log de auditoria com o usuário que os fez.
+func (a *Auth) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if isPublic(r.URL.Path) {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		s := a.session(r)
+		var user User
+		if s != nil {
+			var ok bool
+			if user, ok = a.users.Get(s.Username); !ok {
+				s = nil // conta removida depois do login
+			}
+		}
+		if s == nil {
+			if strings.HasPrefix(r.URL.Path, "/api/") {
+				http.Error(w, "login necessário", http.StatusUnauthorized)
+				return
+			}
+			http.Redirect(w, r, "/login", http.StatusSeeOther)
+			return
+		}
+
+		if info := requestInfoFrom(r); info != nil {
+			info.User = user.Username
+		}
+		ctx := context.WithValue(r.Context(), userKey{}, currentUser{User: user, CSRF: s.CSRF})
+		r = r.WithContext(ctx)
+
+		if isSafeMethod(r.Method) {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		token := r.Header.Get(csrfHeader)
+		if token == "" {
+			token = r.PostFormValue(csrfField)
+		}
+		if subtle.ConstantTimeCompare([]byte(token), []byte(s.CSRF)) != 1 {
+			http.Error(w, "token CSRF inválido", http.StatusForbidden)
+			return
+		}
+
+		rec := &responseRecorder{ResponseWriter: w, status: http.StatusOK}
+		if r.URL.Path != "/logout" && !user.Role.Allows(RoleAuthor) {
+			http.Error(rec, "permissão insuficiente", http.StatusForbidden)
+		} else {
+			next.ServeHTTP(rec, r)
+		}
+		a.record(user.Username, r, rec.status)
+	})
+}
+
+// requireRole restringe o handler a usuários com ao menos o papel role.
+// Se methods for informado, a restrição vale só para esses métodos.
+func requireRole(role Role, handler http.HandlerFunc, methods ...string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if len(methods) == 0 || slices.Contains(methods, r.Method) {
+			u, ok := requestUser(r)
+			if !ok || !u.Role.Allows(role) {
+				http.Error(w, "permissão insuficiente", http.StatusForbidden)
+				return
+			}
+		}
+		handler(w, r)
+	}
+}
+
+// auditEntry é uma linha de audit.jsonl
+type auditEntry struct {
+	Time   time.Time `json:"time"`
+	User   string    `json:"user"`
+	Method string    `json:"method"`
+	Path   string    `json:"path"`
+	Status int       `json:"status"`
+}
+
+// record acrescenta a alteração ao log de auditoria
+func (a *Auth) record(username string, r *http.Request, status int) {
+	data, err := json.Marshal(auditEntry{
+		Time:   time.Now().UTC(),
+		User:   username,
+		Method: r.Method,
+		Path:   r.URL.RequestURI(),
+		Status: status,
+	})
+	if err != nil {
+		return
+	}
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	f, err := os.OpenFile(a.audit, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return
+	}
+	defer f.Close()
+	f.Write(append(data, '\n'))
+}
+
+// loginData alimenta o template login.html
+type loginData struct {
+	Username string
+	Error    string
+	NoUsers  bool
+}
+
+// handleLogin exibe o formulário (GET) e autentica (POST)
+func (a *Auth) handleLogin(w http.ResponseWriter, r *http.Request) {
+	tmpl, err := web.page("login.html")
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	switch r.Method {
+	case http.MethodGet:
+		tmpl.Execute(w, loginData{NoUsers: a.users.Len() == 0})
+
+	case http.MethodPost:
+		username := strings.TrimSpace(r.PostFormValue("username"))
+		user, err := a.users.Authenticate(username, r.PostFormValue("password"))
+		if err != nil {
+			w.WriteHeader(http.StatusUnauthorized)
+			tmpl.Execute(w, loginData{Username: username, Error: err.Error()})
+			return
+		}
+		a.endSession(w, r)
+		a.startSession(w, r, user.Username)
+		http.Redirect(w, r, "/", http.StatusSeeOther)
+
+	default:
+		w.Header().Set("Allow", "GET, POST")
+		http.Error(w, "método não permitido", http.StatusMethodNotAllowed)
+	}
+}
+
+// handleLogout encerra a sessão; passa pelo Middleware, que confere o CSRF
+func (a *Auth) handleLogout(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.Header().Set("Allow", "POST")
+		http.Error(w, "método não permitido", http.StatusMethodNotAllowed)
+		return
+	}
+	a.endSession(w, r)
+	http.Redirect(w, r, "/login", http.StatusSeeOther)
+}
+
+// meResponse descreve o usuário logado para a interface
+type meResponse struct {
+	Username string
+	Role     Role
+}
+
+// handleMe retorna o usuário da sessão
+func handleMe(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		w.Header().Set("Allow", "GET")
+		http.Error(w, "método não permitido", http.StatusMethodNotAllowed)
+		return
+	}
+	u, _ := requestUser(r)
+	writeJSON(w, http.StatusOK, meResponse{Username: u.Username, Role: u.Role})
+}
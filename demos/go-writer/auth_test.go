@@ -0,0 +1,76 @@
+package main
+
+import (
+	"net/http"
+	"testing"
+)
+
+func TestRoleMatrix(t *testing.T) {
+	ts := newTestServer(t)
+	clients := map[Role]*testClient{}
+	for _, role := range []Role{RoleReader, RoleAuthor, RoleEditor} {
+		clients[role] = ts.login(t, string(role))
+	}
+
+	// IDs inexistentes: quem tem permissão passa pela verificação de papel
+	// e recebe 404, quem não tem recebe 403 antes de qualquer efeito
+	const id = "inexistente"
+	title, content := "Título", "texto"
+	routes := []struct {
+		method, path string
+		body         any
+		min          Role
+	}{
+		{http.MethodGet, ts.docPath("sections"), nil, RoleReader},
+		{http.MethodPost, ts.docPath("sections"), sectionRequest{Title: &title, Content: &content}, RoleAuthor},
+		{http.MethodPatch, ts.docPath("sections", id), sectionRequest{Content: &content}, RoleAuthor},
+		{http.MethodPost, ts.docPath("sections", id, "revisions", "1", "restore"), nil, RoleAuthor},
+		{http.MethodPost, ts.docPath("build"), nil, RoleAuthor},
+		{http.MethodDelete, ts.docPath("sections", id), nil, RoleEditor},
+		{http.MethodPost, ts.docPath("sections", "reorder"), reorderRequest{Order: []string{id}}, RoleEditor},
+		{http.MethodPost, ts.docPath("sections", id, "move"), moveRequest{}, RoleEditor},
+		{http.MethodPost, ts.docPath("trash", id, "restore"), nil, RoleEditor},
+		{http.MethodDelete, ts.docPath("trash", id), nil, RoleEditor},
+		{http.MethodDelete, "/api/documents/" + id, nil, RoleEditor},
+	}
+
+	for _, route := range routes {
+		for role, c := range clients {
+			resp := c.do(route.method, route.path, route.body, "If-Match", `"1"`)
+			denied := resp.StatusCode == http.StatusForbidden
+			if denied == role.Allows(route.min) {
+				t.Errorf("%s %s como %s: status %d", route.method, route.path, role, resp.StatusCode)
+			}
+		}
+	}
+}
+
+func TestCSRF(t *testing.T) {
+	ts := newTestServer(t)
+	editor := ts.login(t, "editor")
+	other := ts.login(t, "author")
+	section := editor.addSection("Intro", "v1")
+	path := ts.docPath("sections", section.ID)
+	content := "alterado sem token"
+
+	for name, token := range map[string]string{
+		"sem token":             "",
+		"token inválido":        "x" + editor.csrf,
+		"token de outra sessão": other.csrf,
+	} {
+		resp := editor.do(http.MethodPatch, path, sectionRequest{Content: &content},
+			csrfHeader, token, "If-Match", `"1"`)
+		if resp.StatusCode != http.StatusForbidden {
+			t.Errorf("%s: status %d, esperado 403", name, resp.StatusCode)
+		}
+	}
+
+	if s, err := ts.doc.GetSection(section.ID); err != nil || s.Content != "v1" {
+		t.Fatalf("seção alterada sem CSRF válido: %+v, %v", s, err)
+	}
+
+	// leituras não precisam do token
+	editor.expect(http.StatusOK, http.MethodGet, path, nil, csrfHeader, "")
+	// e o token certo continua valendo
+	editor.expect(http.StatusOK, http.MethodPatch, path, sectionRequest{Content: &content}, "If-Match", `"1"`)
+}
@@ -0,0 +1,123 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+)
+
+// lines junta as linhas com quebra no fim de cada uma
+func lines(ls ...string) string {
+	if len(ls) == 0 {
+		return ""
+	}
+	return strings.Join(ls, "\n") + "\n"
+}
+
+// numbered gera as linhas "prefixo1" a "prefixoN"
+func numbered(prefix string, n int) []string {
+	ls := make([]string, n)
+	for i := range ls {
+		ls[i] = fmt.Sprintf("%s%d", prefix, i+1)
+	}
+	return ls
+}
+
+// As saídas esperadas são as do diff -u do GNU diffutils para os mesmos
+// textos, sem as linhas de cabeçalho com data
+func TestUnifiedDiff(t *testing.T) {
+	tests := []struct {
+		name     string
+		from, to string
+		want     string
+	}{
+		{
+			name: "sem mudança",
+			from: lines("a", "b"),
+			to:   lines("a", "b"),
+			want: "",
+		},
+		{
+			name: "vazios",
+			want: "",
+		},
+		{
+			name: "inserção",
+			from: lines("a", "b", "c"),
+			to:   lines("a", "b", "novo", "c"),
+			want: "@@ -1,3 +1,4 @@\n a\n b\n+novo\n c\n",
+		},
+		{
+			name: "remoção",
+			from: lines("um", "dois", "tres", "quatro", "cinco"),
+			to:   lines("dois", "tres", "quatro", "cinco"),
+			want: "@@ -1,4 +1,3 @@\n-um\n dois\n tres\n quatro\n",
+		},
+		{
+			name: "troca",
+			from: lines("x", "velho", "y"),
+			to:   lines("x", "novo", "y"),
+			want: "@@ -1,3 +1,3 @@\n x\n-velho\n+novo\n y\n",
+		},
+		{
+			name: "a partir de vazio",
+			to:   lines("a", "b"),
+			want: "@@ -0,0 +1,2 @@\n+a\n+b\n",
+		},
+		{
+			name: "até vazio",
+			from: lines("a", "b"),
+			want: "@@ -1,2 +0,0 @@\n-a\n-b\n",
+		},
+		{
+			name: "hunks separados",
+			from: lines(numbered("", 20)...),
+			to: strings.NewReplacer("\n2\n", "\ndois\n", "\n18\n", "\ndezoito\n").
+				Replace(lines(numbered("", 20)...)),
+			want: "@@ -1,5 +1,5 @@\n 1\n-2\n+dois\n 3\n 4\n 5\n" +
+				"@@ -15,6 +15,6 @@\n 15\n 16\n 17\n-18\n+dezoito\n 19\n 20\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := UnifiedDiff("antes", "depois", tt.from, tt.to)
+			want := tt.want
+			if want != "" {
+				want = "--- antes\n+++ depois\n" + want
+			}
+			if got != want {
+				t.Errorf("diff:\n%s\nesperado:\n%s", got, want)
+			}
+		})
+	}
+}
+
+// Duas revisões grandes e sem nada em comum não podem montar a tabela
+// inteira da LCS (20k × 20k linhas passariam de 3 GB)
+func TestUnifiedDiffLargeInput(t *testing.T) {
+	from, to := numbered("antes ", 20000), numbered("depois ", 20000)
+
+	start := time.Now()
+	got := UnifiedDiff("antes", "depois", lines(from...), lines(to...))
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Errorf("diff levou %v", elapsed)
+	}
+	if !strings.HasPrefix(got, "--- antes\n+++ depois\n@@ -1,20000 +1,20000 @@\n-antes 1\n") ||
+		!strings.HasSuffix(got, "+depois 20000\n") {
+		t.Errorf("diff inesperado: %.80q...", got)
+	}
+
+	// o início e o fim iguais não entram na tabela: uma linha trocada no
+	// meio de um texto grande continua com um hunk mínimo
+	changed := append([]string(nil), from...)
+	changed[10000] = "trocada"
+	got = UnifiedDiff("antes", "depois", lines(from...), lines(changed...))
+	want := "--- antes\n+++ depois\n@@ -9998,7 +9998,7 @@\n" +
+		" antes 9998\n antes 9999\n antes 10000\n-antes 10001\n+trocada\n" +
+		" antes 10002\n antes 10003\n antes 10004\n"
+	if got != want {
+		t.Errorf("diff:\n%s\nesperado:\n%s", got, want)
+	}
+}
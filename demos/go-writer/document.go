@@ -0,0 +1,468 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+
+	"github.com/google/uuid"
+)
+
+// TextSection representa uma seção individual do texto. ParentID aponta
+// para a seção que a contém (vazio na raiz), formando partes, capítulos e
+// seções. Version começa em 1 e é incrementada a cada alteração de título
+// ou conteúdo; é ela que vira o ETag usado no controle de concorrência
+// otimista.
+type TextSection struct {
+	ID       string
+	ParentID string
+	Title    string
+	Content  string
+	Order    int
+	Version  int
+	FilePath string
+}
+
+// Erros retornados pelas operações sobre seções
+var (
+	ErrSectionNotFound = errors.New("seção não encontrada")
+	ErrInvalidSection  = errors.New("seção inválida")
+	ErrVersionConflict = errors.New("seção alterada por outra pessoa")
+)
+
+// ConflictError indica que a seção mudou desde a versão que o cliente
+// leu. Current é a cópia atual do servidor.
+type ConflictError struct {
+	Expected int
+	Current  TextSection
+}
+
+func (e *ConflictError) Error() string {
+	return fmt.Sprintf("%v: versão esperada %d, atual %d",
+		ErrVersionConflict, e.Expected, e.Current.Version)
+}
+
+func (e *ConflictError) Is(target error) bool {
+	return target == ErrVersionConflict
+}
+
+// Document representa o documento completo. Dir é o diretório próprio
+// do documento dentro do workspace (documents/<ID>) e AssetsDir o
+// diretório de anexos compartilhado pelo workspace.
+//
+// ID, Dir e AssetsDir não mudam depois da criação. Os demais campos são protegidos
+// por mu: fora deste pacote de métodos, use Info e ListSections.
+type Document struct {
+	ID         string
+	Dir        string
+	AssetsDir  string
+	Title      string
+	Sections   []TextSection
+	OutputPath string
+	Lint       *LintConfig // compartilhada pelos documentos do workspace
+
+	mu    sync.RWMutex
+	index *searchIndex
+
+	hubOnce sync.Once
+	events  *eventHub
+}
+
+// DocumentInfo é o resumo de um documento exposto pela API
+type DocumentInfo struct {
+	ID         string
+	Title      string
+	OutputPath string
+	Sections   int
+}
+
+// Info retorna um resumo consistente do documento
+func (d *Document) Info() DocumentInfo {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+
+	return DocumentInfo{
+		ID:         d.ID,
+		Title:      d.Title,
+		OutputPath: d.OutputPath,
+		Sections:   len(d.Sections),
+	}
+}
+
+// ListSections retorna uma cópia das seções, na ordem do documento
+func (d *Document) ListSections() []TextSection {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+
+	sections := make([]TextSection, len(d.Sections))
+	copy(sections, d.Sections)
+	return sections
+}
+
+// SetTitle altera o título do documento e grava o manifesto
+func (d *Document) SetTitle(title string) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	old := d.Title
+	d.Title = title
+	if err := d.saveManifest(); err != nil {
+		d.Title = old
+		return err
+	}
+	return nil
+}
+
+// SaveDocument combina todas as seções, na ordem definida por Order, em
+// um único Markdown com sumário e o grava em output/<OutputPath>. Os
+// anexos citados pelas seções são copiados para output/assets.
+func (d *Document) SaveDocument() error {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+
+	path := d.outputFile()
+	if err := d.copyAssets(filepath.Dir(path)); err != nil {
+		return err
+	}
+	if err := writeFileAtomic(path, d.markdown(), 0644); err != nil {
+		return fmt.Errorf("erro ao salvar documento %s: %v", path, err)
+	}
+	return nil
+}
+
+// OutputFile retorna o caminho do arquivo final dentro de output/
+func (d *Document) OutputFile() string {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+	return d.outputFile()
+}
+
+func (d *Document) outputFile() string {
+	return filepath.Join(d.Dir, outputDir, filepath.Base(d.OutputPath))
+}
+
+// Markdown gera o documento combinado. As âncoras do sumário seguem o
+// formato do GitHub e consideram também os headings internos das seções,
+// para que títulos repetidos recebam o mesmo sufixo que o GitHub gera.
+func (d *Document) Markdown() []byte {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+	return d.markdown()
+}
+
+func (d *Document) markdown() []byte {
+	sections := d.sortedSections()
+
+	depths := d.depths()
+
+	const tocTitle = "Sumário"
+	slugs := newSlugger()
+	slugs.slug(d.Title)
+	slugs.slug(tocTitle)
+
+	anchors := make([]string, len(sections))
+	for i, s := range sections {
+		anchors[i] = slugs.slug(s.Title)
+		for _, h := range markdownHeadings(s.Content) {
+			slugs.slug(h)
+		}
+	}
+
+	var b bytes.Buffer
+	fmt.Fprintf(&b, "# %s\n\n", d.Title)
+	fmt.Fprintf(&b, "## %s\n\n", tocTitle)
+	for i, s := range sections {
+		indent := strings.Repeat("  ", depths[s.ID])
+		fmt.Fprintf(&b, "%s- [%s](#%s)\n", indent, s.Title, anchors[i])
+	}
+	b.WriteString("\n")
+
+	for _, s := range sections {
+		marker := headingMarker(depths[s.ID])
+		fmt.Fprintf(&b, "%s %s\n\n", marker, s.Title)
+		content := strings.TrimRight(shiftHeadings(s.Content, len(marker)), "\n")
+		if content != "" {
+			b.WriteString(content)
+			b.WriteString("\n\n")
+		}
+	}
+	return b.Bytes()
+}
+
+// AddSection adiciona uma nova seção ao documento, como último filho de
+// parentID ("" para a raiz). author é registrado na primeira revisão da
+// seção.
+func (d *Document) AddSection(title, content, parentID, author string) (*TextSection, error) {
+	if title == "" {
+		return nil, fmt.Errorf("%w: título vazio", ErrInvalidSection)
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	// em pré-ordem, o último filho entra logo depois da subárvore do pai
+	pos := len(d.Sections)
+	if parentID != "" {
+		p := d.indexOf(parentID)
+		if p < 0 {
+			return nil, fmt.Errorf("%w: %s", ErrSectionNotFound, parentID)
+		}
+		depths := d.depths()
+		for pos = p + 1; pos < len(d.Sections); pos++ {
+			if depths[d.Sections[pos].ID] <= depths[parentID] {
+				break
+			}
+		}
+	}
+
+	section := TextSection{
+		ID:       generateID(),
+		ParentID: parentID,
+		Title:    title,
+		Content:  content,
+		Order:    pos,
+		Version:  1,
+	}
+
+	// Criar arquivo para a seção
+	filename := filepath.Join(d.Dir, sectionsDir, fmt.Sprintf("%s.md", section.ID))
+	section.FilePath = filename
+
+	if err := saveSection(&section); err != nil {
+		return nil, err
+	}
+	if err := d.recordRevision(&section, author); err != nil {
+		return nil, err
+	}
+
+	d.Sections = slices.Insert(d.Sections, pos, section)
+	d.searchIndex().add(&section)
+	if err := d.renumber(); err != nil {
+		return nil, err
+	}
+	if err := d.saveManifest(); err != nil {
+		return nil, err
+	}
+
+	created := section
+	d.publish(Event{Type: EventSectionCreated, Section: &created})
+	return &section, nil
+}
+
+// GetSection retorna uma cópia da seção com o ID informado
+func (d *Document) GetSection(id string) (*TextSection, error) {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+
+	i := d.indexOf(id)
+	if i < 0 {
+		return nil, fmt.Errorf("%w: %s", ErrSectionNotFound, id)
+	}
+	section := d.Sections[i]
+	return &section, nil
+}
+
+// checkVersion compara a versão lida pelo cliente com a atual.
+// version 0 dispensa a verificação.
+func (d *Document) checkVersion(i, version int) error {
+	if version != 0 && d.Sections[i].Version != version {
+		return &ConflictError{Expected: version, Current: d.Sections[i]}
+	}
+	return nil
+}
+
+// UpdateSection altera título e/ou conteúdo de uma seção existente.
+// Valores nil mantêm o campo atual. A versão anterior continua
+// disponível no histórico de revisões. Se version for diferente de zero,
+// a alteração só é aplicada se a seção ainda estiver nessa versão.
+func (d *Document) UpdateSection(id string, title, content *string, author string, version int) (*TextSection, error) {
+	if title != nil && *title == "" {
+		return nil, fmt.Errorf("%w: título vazio", ErrInvalidSection)
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	i := d.indexOf(id)
+	if i < 0 {
+		return nil, fmt.Errorf("%w: %s", ErrSectionNotFound, id)
+	}
+	if err := d.checkVersion(i, version); err != nil {
+		return nil, err
+	}
+
+	if err := d.ensureBaseline(&d.Sections[i]); err != nil {
+		return nil, err
+	}
+
+	updated := d.Sections[i]
+	if title != nil {
+		updated.Title = *title
+	}
+	if content != nil {
+		updated.Content = *content
+	}
+	if updated.Title == d.Sections[i].Title && updated.Content == d.Sections[i].Content {
+		return &updated, nil
+	}
+	updated.Version++
+
+	if err := saveSection(&updated); err != nil {
+		return nil, err
+	}
+	if err := d.recordRevision(&updated, author); err != nil {
+		return nil, err
+	}
+	d.Sections[i] = updated
+	d.searchIndex().add(&updated)
+
+	published := updated
+	d.publish(Event{Type: EventSectionUpdated, Section: &published})
+	return &updated, nil
+}
+
+// DeleteSection move uma seção para a lixeira e renumera a ordem das
+// restantes. As subseções sobem um nível e ocupam o lugar dela. version
+// segue a mesma regra de UpdateSection; author fica registrado no item
+// da lixeira.
+func (d *Document) DeleteSection(id string, version int, author string) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	i := d.indexOf(id)
+	if i < 0 {
+		return fmt.Errorf("%w: %s", ErrSectionNotFound, id)
+	}
+	if err := d.checkVersion(i, version); err != nil {
+		return err
+	}
+
+	if err := d.moveToTrash(i, author); err != nil {
+		return err
+	}
+	if err := os.Remove(d.Sections[i].FilePath); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("erro ao remover seção %s: %v", id, err)
+	}
+
+	parentID := d.Sections[i].ParentID
+	d.Sections = append(d.Sections[:i], d.Sections[i+1:]...)
+	d.searchIndex().remove(id)
+
+	var adopted bool
+	for j := range d.Sections {
+		if d.Sections[j].ParentID != id {
+			continue
+		}
+		d.Sections[j].ParentID = parentID
+		d.Sections[j].Order = j
+		if err := saveSection(&d.Sections[j]); err != nil {
+			return err
+		}
+		adopted = true
+	}
+	if err := d.renumber(); err != nil {
+		return err
+	}
+	if err := d.saveManifest(); err != nil {
+		return err
+	}
+
+	d.publish(Event{Type: EventSectionDeleted, ID: id})
+	if adopted {
+		d.publish(Event{Type: EventSectionsReordered, Order: d.sectionIDs()})
+	}
+	return nil
+}
+
+// renumber ajusta Order conforme a posição em d.Sections e regrava
+// apenas as seções cuja ordem mudou
+func (d *Document) renumber() error {
+	for j := range d.Sections {
+		if d.Sections[j].Order == j {
+			continue
+		}
+		d.Sections[j].Order = j
+		if err := saveSection(&d.Sections[j]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// indexOf retorna a posição da seção em d.Sections ou -1
+func (d *Document) indexOf(id string) int {
+	for i, s := range d.Sections {
+		if s.ID == id {
+			return i
+		}
+	}
+	return -1
+}
+
+// ReorderSections reordena as seções do documento sem mudar a árvore.
+// newOrder deve conter exatamente os IDs existentes, cada um uma única
+// vez, com cada seção seguida das suas subseções. Para trocar o pai de
+// uma seção, use MoveSection.
+func (d *Document) ReorderSections(newOrder []string) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if len(newOrder) != len(d.Sections) {
+		return fmt.Errorf("%w: esperados %d IDs, recebidos %d",
+			ErrInvalidSection, len(d.Sections), len(newOrder))
+	}
+
+	reordered := make([]TextSection, 0, len(newOrder))
+	seen := make(map[string]bool, len(newOrder))
+	for _, id := range newOrder {
+		if seen[id] {
+			return fmt.Errorf("%w: ID repetido na nova ordem: %s", ErrInvalidSection, id)
+		}
+		seen[id] = true
+
+		i := d.indexOf(id)
+		if i < 0 {
+			return fmt.Errorf("%w: %s", ErrSectionNotFound, id)
+		}
+		reordered = append(reordered, d.Sections[i])
+	}
+
+	current := d.Sections
+	d.Sections = reordered
+	if !slices.Equal(preorder(d.children()), newOrder) {
+		d.Sections = current
+		return fmt.Errorf("%w: a nova ordem separa seções das suas subseções", ErrInvalidSection)
+	}
+	if err := d.renumber(); err != nil {
+		return err
+	}
+	if err := d.saveManifest(); err != nil {
+		return err
+	}
+
+	d.publish(Event{Type: EventSectionsReordered, Order: append([]string(nil), newOrder...)})
+	return nil
+}
+
+// headingMarker retorna o "##..." de uma seção na profundidade depth: o
+// título do documento é h1, as raízes h2 e assim por diante até h6
+func headingMarker(depth int) string {
+	return strings.Repeat("#", min(depth+2, 6))
+}
+
+// validSectionID indica se o ID pode virar nome de arquivo. Aceita os
+// UUIDs de generateID e os nomes de arquivo de seções antigas, mas não
+// separadores nem "..": o ID entra nos caminhos de revisões e da lixeira.
+func validSectionID(id string) bool {
+	return id != "" && id != "." && id != ".." && !strings.ContainsAny(id, "/\\\x00")
+}
+
+// generateID gera um ID único usando UUID
+func generateID() string {
+	return uuid.New().String()
+}
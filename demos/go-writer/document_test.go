@@ -0,0 +1,249 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"math/rand/v2"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// newTestDocument cria um documento vazio em um workspace temporário
+func newTestDocument(t *testing.T) *Document {
+	t.Helper()
+	ws := NewWorkspace(t.TempDir())
+	if err := initializeWorkspace(ws); err != nil {
+		t.Fatal(err)
+	}
+	doc, err := ws.Create("Teste")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return doc
+}
+
+// reloadDocument lê do disco o documento, como na partida do servidor
+func reloadDocument(t *testing.T, doc *Document) *Document {
+	t.Helper()
+	loaded := &Document{ID: doc.ID, Dir: doc.Dir, AssetsDir: doc.AssetsDir}
+	if err := loaded.Load(); err != nil {
+		t.Fatal(err)
+	}
+	return loaded
+}
+
+// checkOrder confere que Order segue a posição de cada seção
+func checkOrder(t *testing.T, sections []TextSection) {
+	t.Helper()
+	for i, s := range sections {
+		if s.Order != i {
+			t.Errorf("seção %s na posição %d com Order %d", s.ID, i, s.Order)
+		}
+	}
+}
+
+func sectionIDs(sections []TextSection) []string {
+	ids := make([]string, len(sections))
+	for i, s := range sections {
+		ids[i] = s.ID
+	}
+	return ids
+}
+
+func TestConcurrentAddSection(t *testing.T) {
+	doc := newTestDocument(t)
+
+	const n = 50
+	var wg sync.WaitGroup
+	for i := range n {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if _, err := doc.AddSection(fmt.Sprintf("Seção %d", i), "texto", "", "teste"); err != nil {
+				t.Error(err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	sections := doc.ListSections()
+	if len(sections) != n {
+		t.Fatalf("esperadas %d seções, obtidas %d", n, len(sections))
+	}
+	checkOrder(t, sections)
+
+	loaded := reloadDocument(t, doc)
+	if !slices.Equal(sectionIDs(loaded.Sections), sectionIDs(sections)) {
+		t.Error("a ordem gravada em disco difere da ordem em memória")
+	}
+}
+
+func TestConcurrentUpdateSection(t *testing.T) {
+	doc := newTestDocument(t)
+	section, err := doc.AddSection("Intro", "v1", "", "teste")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// todos leram a versão 1: só uma gravação pode vencer
+	const n = 20
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	var saved, conflicts int
+	for i := range n {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			content := fmt.Sprintf("texto %d", i)
+			_, err := doc.UpdateSection(section.ID, nil, &content, "teste", section.Version)
+
+			mu.Lock()
+			defer mu.Unlock()
+			var conflict *ConflictError
+			switch {
+			case err == nil:
+				saved++
+			case errors.As(err, &conflict):
+				conflicts++
+				if conflict.Current.Version != 2 {
+					t.Errorf("conflito com versão atual %d, esperada 2", conflict.Current.Version)
+				}
+			default:
+				t.Error(err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	if saved != 1 || conflicts != n-1 {
+		t.Errorf("%d gravações e %d conflitos, esperados 1 e %d", saved, conflicts, n-1)
+	}
+
+	// sem versão, todas entram e cada uma gera uma revisão
+	for i := range n {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			content := fmt.Sprintf("sem versão %d", i)
+			if _, err := doc.UpdateSection(section.ID, nil, &content, "teste", 0); err != nil {
+				t.Error(err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	current, err := doc.GetSection(section.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if current.Version != n+2 {
+		t.Errorf("versão final %d, esperada %d", current.Version, n+2)
+	}
+	revisions, err := doc.Revisions(section.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(revisions) != n+2 {
+		t.Errorf("%d revisões, esperadas %d", len(revisions), n+2)
+	}
+}
+
+func TestConcurrentReorderSections(t *testing.T) {
+	doc := newTestDocument(t)
+	for i := range 10 {
+		if _, err := doc.AddSection(fmt.Sprintf("Seção %d", i), "", "", "teste"); err != nil {
+			t.Fatal(err)
+		}
+	}
+	ids := sectionIDs(doc.ListSections())
+
+	var wg sync.WaitGroup
+	for range 20 {
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			order := slices.Clone(ids)
+			rand.Shuffle(len(order), func(i, j int) { order[i], order[j] = order[j], order[i] })
+			if err := doc.ReorderSections(order); err != nil {
+				t.Error(err)
+			}
+		}()
+		go func() {
+			defer wg.Done()
+			content := "editado durante a reordenação"
+			if _, err := doc.UpdateSection(ids[rand.IntN(len(ids))], nil, &content, "teste", 0); err != nil {
+				t.Error(err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	sections := doc.ListSections()
+	checkOrder(t, sections)
+	got := sectionIDs(sections)
+	if !slices.Equal(slices.Sorted(slices.Values(got)), slices.Sorted(slices.Values(ids))) {
+		t.Fatalf("seções perdidas ou duplicadas: %v", got)
+	}
+
+	loaded := reloadDocument(t, doc)
+	if !slices.Equal(sectionIDs(loaded.Sections), got) {
+		t.Error("a ordem gravada em disco difere da ordem em memória")
+	}
+	checkOrder(t, loaded.Sections)
+}
+
+// Os headings do conteúdo ficam abaixo do título da própria seção no
+// documento gerado, seja qual for a profundidade dela na árvore
+func TestMarkdownShiftsContentHeadings(t *testing.T) {
+	doc := newTestDocument(t)
+	part, err := doc.AddSection("Parte 1", "# Visão geral\n\nTexto.", "", "teste")
+	if err != nil {
+		t.Fatal(err)
+	}
+	chapter, err := doc.AddSection("Capítulo 1", "", part.ID, "teste")
+	if err != nil {
+		t.Fatal(err)
+	}
+	content := "# **1.1 Introdução**\n\nTexto.\n\n## Detalhe\n\n" +
+		"````markdown\n```go\n# dentro do bloco\n```\n````\n\n### Mais fundo\n"
+	if _, err := doc.AddSection("Seção 1.1", content, chapter.ID, "teste"); err != nil {
+		t.Fatal(err)
+	}
+	// conteúdo que já está abaixo do título não muda
+	if _, err := doc.AddSection("Apêndice", "### Notas\n\n#### Fontes", "", "teste"); err != nil {
+		t.Fatal(err)
+	}
+
+	var got []string
+	var fences fenceScanner
+	for _, line := range strings.Split(string(doc.Markdown()), "\n") {
+		if fences.scan(line) != textLine {
+			continue
+		}
+		if level, text, ok := parseHeading(line); ok {
+			got = append(got, fmt.Sprintf("%d %s", level, text))
+		}
+	}
+	want := []string{
+		"1 Teste",
+		"2 Sumário",
+		"2 Parte 1",
+		"3 Visão geral",
+		"3 Capítulo 1",
+		"4 Seção 1.1",
+		"5 **1.1 Introdução**",
+		"6 Detalhe",
+		"6 Mais fundo",
+		"2 Apêndice",
+		"3 Notas",
+		"4 Fontes",
+	}
+	if !slices.Equal(got, want) {
+		t.Errorf("headings:\n%q\nesperados:\n%q", got, want)
+	}
+	if !strings.Contains(string(doc.Markdown()), "```go\n# dentro do bloco\n```\n") {
+		t.Error("bloco de código alterado")
+	}
+}
@@ -0,0 +1,320 @@
+package main
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"hash/crc32"
+	"html"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	xhtml "golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+)
+
+// epubLanguage é o idioma declarado no pacote e nas páginas
+const epubLanguage = "pt-BR"
+
+// epubCSS complementa o CSS do realce de sintaxe nas páginas do livro
+const epubCSS = `body { font-family: serif; line-height: 1.5; }
+h1, h2, h3, h4 { font-family: sans-serif; }
+pre { white-space: pre-wrap; font-size: 0.85em; }
+code { font-family: monospace; }
+table { border-collapse: collapse; }
+th, td { border: 1px solid #999; padding: 0.2em 0.5em; }
+.cover { margin: 0; padding: 0; text-align: center; }
+.cover img { max-width: 100%; max-height: 100%; }
+`
+
+// epubContainer aponta o leitor para o pacote OPF
+const epubContainer = `<?xml version="1.0" encoding="UTF-8"?>
+<container version="1.0" xmlns="urn:oasis:names:tc:opendocument:xmlns:container">
+  <rootfiles>
+    <rootfile full-path="OEBPS/content.opf" media-type="application/oebps-package+xml"/>
+  </rootfiles>
+</container>
+`
+
+// opfPackage é o content.opf do EPUB 3
+type opfPackage struct {
+	XMLName          xml.Name    `xml:"http://www.idpf.org/2007/opf package"`
+	Version          string      `xml:"version,attr"`
+	UniqueIdentifier string      `xml:"unique-identifier,attr"`
+	Lang             string      `xml:"xml:lang,attr"`
+	Metadata         opfMetadata `xml:"metadata"`
+	Manifest         []opfItem   `xml:"manifest>item"`
+	Spine            []opfRef    `xml:"spine>itemref"`
+}
+
+type opfMetadata struct {
+	DC         string    `xml:"xmlns:dc,attr"`
+	Identifier opfID     `xml:"dc:identifier"`
+	Title      string    `xml:"dc:title"`
+	Language   string    `xml:"dc:language"`
+	Meta       []opfMeta `xml:"meta"`
+}
+
+type opfID struct {
+	ID    string `xml:"id,attr"`
+	Value string `xml:",chardata"`
+}
+
+type opfMeta struct {
+	Property string `xml:"property,attr,omitempty"`
+	Name     string `xml:"name,attr,omitempty"`
+	Content  string `xml:"content,attr,omitempty"`
+	Value    string `xml:",chardata"`
+}
+
+type opfItem struct {
+	ID         string `xml:"id,attr"`
+	Href       string `xml:"href,attr"`
+	MediaType  string `xml:"media-type,attr"`
+	Properties string `xml:"properties,attr,omitempty"`
+}
+
+type opfRef struct {
+	IDRef string `xml:"idref,attr"`
+}
+
+// epubRoot é o diretório do zip com o pacote e o conteúdo
+const epubRoot = "OEBPS/"
+
+// epubFile é um arquivo do zip, com o caminho completo
+type epubFile struct {
+	name string
+	data []byte
+}
+
+// ExportEPUB gera output/<nome>.epub: uma página XHTML por seção, na
+// ordem do documento, nav.xhtml com o sumário, as imagens citadas pelas
+// seções e, se o documento tiver capa, cover.xhtml com a imagem.
+func (d *Document) ExportEPUB() (string, error) {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+
+	data, err := d.epub(time.Now().UTC())
+	if err != nil {
+		return "", fmt.Errorf("erro ao gerar EPUB: %v", err)
+	}
+
+	path := d.exportFile(".epub")
+	if err := writeFileAtomic(path, data, 0644); err != nil {
+		return "", fmt.Errorf("erro ao salvar EPUB %s: %v", path, err)
+	}
+	return path, nil
+}
+
+func (d *Document) epub(modified time.Time) ([]byte, error) {
+	css, err := highlightCSS()
+	if err != nil {
+		return nil, err
+	}
+
+	pkg := opfPackage{
+		Version:          "3.0",
+		UniqueIdentifier: "book-id",
+		Lang:             epubLanguage,
+		Metadata: opfMetadata{
+			DC:         "http://purl.org/dc/elements/1.1/",
+			Identifier: opfID{ID: "book-id", Value: bookIdentifier(d.ID)},
+			Title:      d.Title,
+			Language:   epubLanguage,
+			Meta: []opfMeta{
+				{Property: "dcterms:modified", Value: modified.Format(time.RFC3339)},
+			},
+		},
+		Manifest: []opfItem{
+			{ID: "nav", Href: "nav.xhtml", MediaType: "application/xhtml+xml", Properties: "nav"},
+			{ID: "style", Href: "style.css", MediaType: "text/css"},
+		},
+	}
+	files := []epubFile{
+		{"META-INF/container.xml", []byte(epubContainer)},
+		{epubRoot + "style.css", append([]byte(epubCSS), css...)},
+	}
+
+	if cover := d.coverFile(); cover != "" {
+		image, err := os.ReadFile(cover)
+		if err != nil {
+			return nil, err
+		}
+		name := filepath.Base(cover)
+		mediaType := "image/jpeg"
+		if filepath.Ext(name) == ".png" {
+			mediaType = "image/png"
+		}
+		pkg.Metadata.Meta = append(pkg.Metadata.Meta, opfMeta{Name: "cover", Content: "cover-image"})
+		pkg.Manifest = append(pkg.Manifest,
+			opfItem{ID: "cover-image", Href: name, MediaType: mediaType, Properties: "cover-image"},
+			opfItem{ID: "cover", Href: "cover.xhtml", MediaType: "application/xhtml+xml"},
+		)
+		pkg.Spine = append(pkg.Spine, opfRef{IDRef: "cover"})
+		files = append(files,
+			epubFile{epubRoot + name, image},
+			epubFile{epubRoot + "cover.xhtml", xhtmlPage(d.Title, "cover",
+				fmt.Sprintf(`<img src="%s" alt="%s"/>`, name, html.EscapeString(d.Title)))},
+		)
+	}
+
+	pkg.Spine = append(pkg.Spine, opfRef{IDRef: "nav"})
+
+	// só imagens: o EPUB não aceita outros tipos sem fallback
+	for i, name := range d.referencedAssets() {
+		if !isImage(name) {
+			continue
+		}
+		data, err := os.ReadFile(filepath.Join(d.AssetsDir, name))
+		if err != nil {
+			return nil, err
+		}
+		href := assetsDir + "/" + name
+		pkg.Manifest = append(pkg.Manifest, opfItem{
+			ID: fmt.Sprintf("asset-%03d", i+1), Href: href, MediaType: assetMediaType(name),
+		})
+		files = append(files, epubFile{epubRoot + href, data})
+	}
+
+	// o sumário segue a árvore de seções com <ol> aninhadas; em pré-ordem
+	// a profundidade cresce no máximo um nível por vez
+	var nav strings.Builder
+	fmt.Fprintf(&nav, "<nav epub:type=\"toc\" id=\"toc\">\n<h1>Sumário</h1>\n<ol>\n")
+	depths := d.depths()
+	prev := -1
+	for i, s := range d.sortedSections() {
+		id := fmt.Sprintf("section-%03d", i+1)
+		name := id + ".xhtml"
+		depth := depths[s.ID]
+
+		switch {
+		case depth > prev && prev >= 0:
+			nav.WriteString("\n<ol>\n")
+		case depth <= prev:
+			nav.WriteString("</li>\n")
+			nav.WriteString(strings.Repeat("</ol>\n</li>\n", prev-depth))
+		}
+		prev = depth
+
+		body, err := sectionXHTML(s, depth)
+		if err != nil {
+			return nil, fmt.Errorf("seção %s: %v", s.ID, err)
+		}
+		files = append(files, epubFile{epubRoot + name, xhtmlPage(s.Title, "", body)})
+		pkg.Manifest = append(pkg.Manifest, opfItem{ID: id, Href: name, MediaType: "application/xhtml+xml"})
+		pkg.Spine = append(pkg.Spine, opfRef{IDRef: id})
+		fmt.Fprintf(&nav, "<li><a href=\"%s\">%s</a>", name, html.EscapeString(s.Title))
+	}
+	if prev >= 0 {
+		nav.WriteString("</li>\n")
+		nav.WriteString(strings.Repeat("</ol>\n</li>\n", prev))
+	}
+	nav.WriteString("</ol>\n</nav>")
+	files = append(files, epubFile{epubRoot + "nav.xhtml", xhtmlPage(d.Title, "", nav.String())})
+
+	opf, err := xml.MarshalIndent(pkg, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	files = append(files, epubFile{epubRoot + "content.opf", append([]byte(xml.Header), opf...)})
+
+	return zipEPUB(files, modified)
+}
+
+// bookIdentifier usa o ID do documento como identificador único do livro
+func bookIdentifier(id string) string {
+	if _, err := uuid.Parse(id); err == nil {
+		return "urn:uuid:" + id
+	}
+	return "urn:go-writer:" + id
+}
+
+// zipEPUB monta o arquivo: mimetype precisa ser o primeiro e sem
+// compressão, para que leitores reconheçam o formato pelos bytes iniciais
+func zipEPUB(files []epubFile, modified time.Time) ([]byte, error) {
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+
+	mimetype := []byte("application/epub+zip")
+	w, err := zw.CreateRaw(&zip.FileHeader{
+		Name:               "mimetype",
+		Method:             zip.Store,
+		CRC32:              crc32.ChecksumIEEE(mimetype),
+		CompressedSize64:   uint64(len(mimetype)),
+		UncompressedSize64: uint64(len(mimetype)),
+	})
+	if err != nil {
+		return nil, err
+	}
+	if _, err := w.Write(mimetype); err != nil {
+		return nil, err
+	}
+
+	for _, f := range files {
+		w, err := zw.CreateHeader(&zip.FileHeader{
+			Name:     f.name,
+			Method:   zip.Deflate,
+			Modified: modified,
+		})
+		if err != nil {
+			return nil, err
+		}
+		if _, err := w.Write(f.data); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := zw.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// xhtmlPage envolve o corpo em um documento XHTML 5 completo
+func xhtmlPage(title, class, body string) []byte {
+	var b bytes.Buffer
+	b.WriteString(xml.Header)
+	b.WriteString("<!DOCTYPE html>\n")
+	fmt.Fprintf(&b, "<html xmlns=\"http://www.w3.org/1999/xhtml\" xmlns:epub=\"http://www.idpf.org/2007/ops\" lang=\"%s\" xml:lang=\"%s\">\n",
+		epubLanguage, epubLanguage)
+	fmt.Fprintf(&b, "<head>\n<meta charset=\"UTF-8\"/>\n<title>%s</title>\n", html.EscapeString(title))
+	b.WriteString("<link rel=\"stylesheet\" type=\"text/css\" href=\"style.css\"/>\n</head>\n")
+	if class != "" {
+		fmt.Fprintf(&b, "<body class=\"%s\">\n", class)
+	} else {
+		b.WriteString("<body>\n")
+	}
+	b.WriteString(body)
+	b.WriteString("\n</body>\n</html>\n")
+	return b.Bytes()
+}
+
+// sectionXHTML renderiza a seção como no preview e reserializa o HTML em
+// XHTML bem formado (elementos vazios fechados, atributos com valor). O
+// título usa o nível de heading da profundidade da seção na árvore.
+func sectionXHTML(s TextSection, depth int) (string, error) {
+	rendered, err := RenderMarkdown(s.Content)
+	if err != nil {
+		return "", err
+	}
+
+	body := &xhtml.Node{Type: xhtml.ElementNode, Data: "body", DataAtom: atom.Body}
+	nodes, err := xhtml.ParseFragment(bytes.NewReader(rendered), body)
+	if err != nil {
+		return "", err
+	}
+
+	var b strings.Builder
+	level := min(depth+1, 6)
+	fmt.Fprintf(&b, "<h%d>%s</h%d>\n", level, html.EscapeString(s.Title), level)
+	for _, n := range nodes {
+		if err := xhtml.Render(&b, n); err != nil {
+			return "", err
+		}
+	}
+	return b.String(), nil
+}
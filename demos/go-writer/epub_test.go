@@ -0,0 +1,156 @@
+package main
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"html"
+	"image"
+	"image/png"
+	"io"
+	"path"
+	"strings"
+	"testing"
+	"time"
+)
+
+// testPNG gera uma imagem PNG de 1x1
+func testPNG(t *testing.T) []byte {
+	t.Helper()
+	var b bytes.Buffer
+	if err := png.Encode(&b, image.NewGray(image.Rect(0, 0, 1, 1))); err != nil {
+		t.Fatal(err)
+	}
+	return b.Bytes()
+}
+
+// readZipFile lê um arquivo do zip pelo nome
+func readZipFile(t *testing.T, zr *zip.Reader, name string) []byte {
+	t.Helper()
+	f, err := zr.Open(name)
+	if err != nil {
+		t.Fatalf("%s ausente no EPUB: %v", name, err)
+	}
+	defer f.Close()
+	data, err := io.ReadAll(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return data
+}
+
+func TestEPUBStructure(t *testing.T) {
+	ws := NewWorkspace(t.TempDir())
+	if err := initializeWorkspace(ws); err != nil {
+		t.Fatal(err)
+	}
+	doc, err := ws.Create("Livro de Teste")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := doc.SetCover(testPNG(t)); err != nil {
+		t.Fatal(err)
+	}
+	// um byte a mais para não ter o mesmo conteúdo da capa
+	asset, err := ws.SaveAsset(append(testPNG(t), 0), "figura.png")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	part, err := doc.AddSection("Parte 1", "", "", "teste")
+	if err != nil {
+		t.Fatal(err)
+	}
+	chapter, err := doc.AddSection("Capítulo 1 & <introdução>", "Texto com "+asset.Markdown, part.ID, "teste")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := doc.AddSection("Seção 1.1", "```go\nfmt.Println(1)\n```\n", chapter.ID, "teste"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := doc.AddSection("Apêndice", "Fim.", "", "teste"); err != nil {
+		t.Fatal(err)
+	}
+
+	data, err := doc.epub(time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC))
+	if err != nil {
+		t.Fatal(err)
+	}
+	zr, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// mimetype precisa ser o primeiro arquivo, sem compressão
+	first := zr.File[0]
+	if first.Name != "mimetype" || first.Method != zip.Store {
+		t.Fatalf("primeiro arquivo %s com método %d, esperado mimetype sem compressão", first.Name, first.Method)
+	}
+	if got := string(readZipFile(t, zr, "mimetype")); got != "application/epub+zip" {
+		t.Errorf("mimetype = %q", got)
+	}
+
+	var container struct {
+		Rootfiles []struct {
+			FullPath string `xml:"full-path,attr"`
+		} `xml:"rootfiles>rootfile"`
+	}
+	if err := xml.Unmarshal(readZipFile(t, zr, "META-INF/container.xml"), &container); err != nil {
+		t.Fatal(err)
+	}
+	if len(container.Rootfiles) != 1 || container.Rootfiles[0].FullPath != "OEBPS/content.opf" {
+		t.Fatalf("container.xml aponta para %+v", container.Rootfiles)
+	}
+
+	var pkg struct {
+		Manifest []opfItem `xml:"manifest>item"`
+		Spine    []opfRef  `xml:"spine>itemref"`
+	}
+	if err := xml.Unmarshal(readZipFile(t, zr, "OEBPS/content.opf"), &pkg); err != nil {
+		t.Fatal(err)
+	}
+	hrefs := make(map[string]string, len(pkg.Manifest))
+	for _, item := range pkg.Manifest {
+		hrefs[item.ID] = item.Href
+		readZipFile(t, zr, path.Join("OEBPS", item.Href))
+	}
+	if _, ok := hrefs["asset-001"]; !ok {
+		t.Error("imagem citada pela seção fora do manifesto")
+	}
+
+	// capa, sumário e uma página por seção, na ordem do documento
+	sections := doc.ListSections()
+	want := []string{"cover", "nav"}
+	for i := range sections {
+		want = append(want, fmt.Sprintf("section-%03d", i+1))
+	}
+	if len(pkg.Spine) != len(want) {
+		t.Fatalf("spine com %d itens, esperados %d", len(pkg.Spine), len(want))
+	}
+	for i, ref := range pkg.Spine {
+		if ref.IDRef != want[i] {
+			t.Errorf("spine[%d] = %s, esperado %s", i, ref.IDRef, want[i])
+		}
+		if _, ok := hrefs[ref.IDRef]; !ok {
+			t.Errorf("spine[%d] = %s fora do manifesto", i, ref.IDRef)
+		}
+	}
+	for i, s := range sections {
+		page := string(readZipFile(t, zr, path.Join("OEBPS", hrefs[want[i+2]])))
+		if !strings.Contains(page, "<title>"+html.EscapeString(s.Title)+"</title>") {
+			t.Errorf("página %d não é da seção %q", i+1, s.Title)
+		}
+	}
+
+	nav := readZipFile(t, zr, "OEBPS/nav.xhtml")
+	if err := xml.Unmarshal(nav, new(struct{})); err != nil {
+		t.Errorf("nav.xhtml não é XML válido: %v", err)
+	}
+	for i, s := range sections {
+		link := `<a href="` + hrefs[want[i+2]] + `">` + html.EscapeString(s.Title) + `</a>`
+		if !bytes.Contains(nav, []byte(link)) {
+			t.Errorf("nav.xhtml sem %s", link)
+		}
+	}
+}
@@ -0,0 +1,172 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// Tipos de evento enviados aos editores conectados
+const (
+	EventSectionCreated    = "section.created"
+	EventSectionUpdated    = "section.updated"
+	EventSectionDeleted    = "section.deleted"
+	EventSectionsReordered = "sections.reordered"
+)
+
+// eventBuffer é quantos eventos um cliente lento pode acumular antes de
+// ser desconectado; ao reconectar ele recarrega a lista inteira
+const eventBuffer = 64
+
+// sseHeartbeat mantém a conexão viva atrás de proxies
+const sseHeartbeat = 25 * time.Second
+
+// Event é uma alteração em um documento, propagada em tempo real
+type Event struct {
+	Type    string
+	Section *TextSection `json:",omitempty"`
+	ID      string       `json:",omitempty"`
+	Order   []string     `json:",omitempty"`
+}
+
+// eventHub distribui os eventos de um documento para os inscritos
+type eventHub struct {
+	mu     sync.Mutex
+	subs   map[chan Event]struct{}
+	closed bool // documento excluído: não aceita mais inscritos
+}
+
+func newEventHub() *eventHub {
+	return &eventHub{subs: make(map[chan Event]struct{})}
+}
+
+// subscribe registra um novo ouvinte. A função retornada cancela a
+// inscrição; o canal é fechado pelo hub. Num hub já fechado o canal
+// volta fechado.
+func (h *eventHub) subscribe() (<-chan Event, func()) {
+	ch := make(chan Event, eventBuffer)
+
+	h.mu.Lock()
+	if h.closed {
+		close(ch)
+	} else {
+		h.subs[ch] = struct{}{}
+	}
+	h.mu.Unlock()
+
+	return ch, func() {
+		h.mu.Lock()
+		defer h.mu.Unlock()
+		if _, ok := h.subs[ch]; ok {
+			delete(h.subs, ch)
+			close(ch)
+		}
+	}
+}
+
+// publish entrega o evento sem bloquear: quem não acompanha é removido
+func (h *eventHub) publish(e Event) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	for ch := range h.subs {
+		select {
+		case ch <- e:
+		default:
+			delete(h.subs, ch)
+			close(ch)
+		}
+	}
+}
+
+// close fecha o canal de todos os inscritos, encerrando seus streams, e
+// recusa inscrições futuras
+func (h *eventHub) close() {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	for ch := range h.subs {
+		delete(h.subs, ch)
+		close(ch)
+	}
+	h.closed = true
+}
+
+// Subscribe inscreve um ouvinte nos eventos do documento
+func (d *Document) Subscribe() (<-chan Event, func()) {
+	return d.hub().subscribe()
+}
+
+// publish envia um evento do documento; é seguro chamá-lo com d.mu travado
+func (d *Document) publish(e Event) {
+	d.hub().publish(e)
+}
+
+// hub cria o eventHub sob demanda, para que o valor zero de Document
+// continue utilizável
+func (d *Document) hub() *eventHub {
+	d.hubOnce.Do(func() {
+		d.events = newEventHub()
+	})
+	return d.events
+}
+
+// handleEvents mantém um stream Server-Sent Events com as alterações do
+// documento. O navegador reconecta sozinho se a conexão cair.
+func handleEvents(doc *Document) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			w.Header().Set("Allow", "GET")
+			http.Error(w, "método não permitido", http.StatusMethodNotAllowed)
+			return
+		}
+
+		// o stream não tem prazo: remove os limites de leitura e escrita
+		// do servidor só para esta conexão
+		rc := http.NewResponseController(w)
+		rc.SetReadDeadline(time.Time{})
+		rc.SetWriteDeadline(time.Time{})
+
+		events, cancel := doc.Subscribe()
+		defer cancel()
+
+		w.Header().Set("Content-Type", "text/event-stream")
+		w.Header().Set("Cache-Control", "no-cache")
+		w.Header().Set("Connection", "keep-alive")
+		w.WriteHeader(http.StatusOK)
+		// comentário inicial para o cliente saber que a conexão abriu
+		fmt.Fprint(w, ": conectado\n\n")
+		if err := rc.Flush(); err != nil {
+			return
+		}
+
+		heartbeat := time.NewTicker(sseHeartbeat)
+		defer heartbeat.Stop()
+
+		for {
+			select {
+			case <-r.Context().Done():
+				return
+
+			case <-heartbeat.C:
+				fmt.Fprint(w, ": ping\n\n")
+				rc.Flush()
+
+			case e, ok := <-events:
+				if !ok {
+					// cliente lento, ou documento excluído: encerra para
+					// que reconecte e recarregue
+					return
+				}
+				data, err := json.Marshal(e)
+				if err != nil {
+					continue
+				}
+				fmt.Fprintf(w, "event: %s\ndata: %s\n\n", e.Type, data)
+				rc.Flush()
+			}
+		}
+	}
+}
@@ -0,0 +1,198 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"io"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+// sseFrame é um evento lido do stream, já com o JSON decodificado
+type sseFrame struct {
+	Type  string
+	Event Event
+}
+
+// readFrames lê o stream em segundo plano e entrega cada evento no canal,
+// que é fechado quando o servidor encerra a conexão. Comentários (": ping")
+// são ignorados.
+func readFrames(t *testing.T, body io.Reader) <-chan sseFrame {
+	frames := make(chan sseFrame)
+	go func() {
+		defer close(frames)
+		scanner := bufio.NewScanner(body)
+		var frame sseFrame
+		var data string
+		for scanner.Scan() {
+			line := scanner.Text()
+			switch {
+			case line == "":
+				if frame.Type == "" {
+					continue
+				}
+				if err := json.Unmarshal([]byte(data), &frame.Event); err != nil {
+					t.Errorf("data inválido em %s: %v", frame.Type, err)
+				}
+				frames <- frame
+				frame, data = sseFrame{}, ""
+			case strings.HasPrefix(line, ":"):
+			case strings.HasPrefix(line, "event: "):
+				frame.Type = strings.TrimPrefix(line, "event: ")
+			case strings.HasPrefix(line, "data: "):
+				data = strings.TrimPrefix(line, "data: ")
+			}
+		}
+	}()
+	return frames
+}
+
+// nextFrame espera o próximo evento do stream
+func nextFrame(t *testing.T, frames <-chan sseFrame) sseFrame {
+	t.Helper()
+	select {
+	case frame, ok := <-frames:
+		if !ok {
+			t.Fatal("stream encerrado antes do evento")
+		}
+		return frame
+	case <-time.After(5 * time.Second):
+		t.Fatal("nenhum evento em 5s")
+	}
+	return sseFrame{}
+}
+
+// subscribe abre o stream de eventos e espera o comentário inicial, para
+// que as alterações feitas depois já cheguem a este cliente
+func (c *testClient) subscribe() (*http.Response, <-chan sseFrame) {
+	c.t.Helper()
+	resp := c.expect(http.StatusOK, http.MethodGet, c.ts.docPath("events"), nil)
+	if ct := resp.Header.Get("Content-Type"); ct != "text/event-stream" {
+		c.t.Fatalf("Content-Type = %s", ct)
+	}
+	br := bufio.NewReader(resp.Body)
+	if line, err := br.ReadString('\n'); err != nil || line != ": conectado\n" {
+		c.t.Fatalf("início do stream = %q, %v", line, err)
+	}
+	return resp, readFrames(c.t, br)
+}
+
+func TestEventsStream(t *testing.T) {
+	ts := newTestServer(t)
+	editor := ts.login(t, "editor")
+	_, frames := ts.login(t, "reader").subscribe()
+
+	first := editor.addSection("Intro", "v1")
+	frame := nextFrame(t, frames)
+	if frame.Type != EventSectionCreated || frame.Event.Section == nil || frame.Event.Section.ID != first.ID {
+		t.Fatalf("criação: %+v", frame)
+	}
+	second := editor.addSection("Fim", "")
+	nextFrame(t, frames)
+
+	content := "v2"
+	editor.expect(http.StatusOK, http.MethodPatch, ts.docPath("sections", first.ID),
+		sectionRequest{Content: &content}, "If-Match", `"1"`)
+	frame = nextFrame(t, frames)
+	if frame.Type != EventSectionUpdated || frame.Event.Section.Content != "v2" || frame.Event.Section.Version != 2 {
+		t.Fatalf("edição: %+v", frame)
+	}
+
+	order := []string{second.ID, first.ID}
+	editor.expect(http.StatusOK, http.MethodPost, ts.docPath("sections", "reorder"), reorderRequest{Order: order})
+	frame = nextFrame(t, frames)
+	if frame.Type != EventSectionsReordered || !slices.Equal(frame.Event.Order, order) {
+		t.Fatalf("reordenação: %+v", frame)
+	}
+
+	editor.expect(http.StatusNoContent, http.MethodDelete, ts.docPath("sections", second.ID), nil, "If-Match", `"1"`)
+	frame = nextFrame(t, frames)
+	if frame.Type != EventSectionDeleted || frame.Event.ID != second.ID {
+		t.Fatalf("exclusão: %+v", frame)
+	}
+}
+
+func TestEventsStreamEndsWhenClientIsDropped(t *testing.T) {
+	ts := newTestServer(t)
+	_, frames := ts.login(t, "reader").subscribe()
+
+	// o que publish faz com quem não acompanha: remove e fecha o canal
+	h := ts.doc.hub()
+	h.mu.Lock()
+	for ch := range h.subs {
+		delete(h.subs, ch)
+		close(ch)
+	}
+	h.mu.Unlock()
+
+	select {
+	case frame, ok := <-frames:
+		if ok {
+			t.Fatalf("evento inesperado: %+v", frame)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("o stream continuou aberto depois de o cliente ser removido")
+	}
+}
+
+func TestEventHubDropsSlowSubscriber(t *testing.T) {
+	h := newEventHub()
+	slow, cancelSlow := h.subscribe()
+	fast, cancelFast := h.subscribe()
+	defer cancelFast()
+
+	received := 0
+	for i := range eventBuffer + 1 {
+		h.publish(Event{Type: EventSectionDeleted, ID: strconv.Itoa(i)})
+		<-fast // o cliente rápido lê cada evento
+		received++
+	}
+
+	// o lento recebe o que cabia no buffer e depois o canal fechado
+	for range eventBuffer {
+		if _, ok := <-slow; !ok {
+			t.Fatal("canal fechado antes de entregar o buffer")
+		}
+	}
+	if _, ok := <-slow; ok {
+		t.Fatal("cliente lento não foi desconectado")
+	}
+	cancelSlow() // cancelar depois da remoção não pode fechar de novo
+
+	h.publish(Event{Type: EventSectionsReordered})
+	if e := <-fast; e.Type != EventSectionsReordered || received != eventBuffer+1 {
+		t.Errorf("cliente rápido perdeu eventos: %d recebidos, último %+v", received, e)
+	}
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if len(h.subs) != 1 {
+		t.Errorf("%d inscritos, esperado 1", len(h.subs))
+	}
+}
+
+func TestEventsStreamEndsWhenDocumentIsDeleted(t *testing.T) {
+	ts := newTestServer(t)
+	_, frames := ts.login(t, "reader").subscribe()
+
+	ts.login(t, "editor").expect(http.StatusNoContent, http.MethodDelete, "/api/documents/"+ts.doc.ID, nil)
+
+	select {
+	case frame, ok := <-frames:
+		if ok {
+			t.Fatalf("evento inesperado: %+v", frame)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("o stream continuou aberto depois da exclusão do documento")
+	}
+
+	// quem ainda tinha o documento em mãos não consegue mais se inscrever
+	events, cancel := ts.doc.Subscribe()
+	defer cancel()
+	if _, ok := <-events; ok {
+		t.Error("inscrição aceita em documento excluído")
+	}
+}
@@ -0,0 +1,194 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// Erros retornados pela exportação e pela capa
+var (
+	ErrUnknownFormat = errors.New("formato de exportação desconhecido")
+	ErrInvalidCover  = errors.New("a capa deve ser uma imagem JPEG ou PNG")
+)
+
+// maxCoverBytes limita o tamanho da imagem de capa enviada
+const maxCoverBytes = 10 << 20
+
+// coverName é o nome, sem extensão, da capa dentro do diretório do documento
+const coverName = "cover"
+
+// coverTypes são os formatos de capa aceitos e a extensão usada no disco
+var coverTypes = map[string]string{
+	"image/jpeg": ".jpg",
+	"image/png":  ".png",
+}
+
+// exporter gera o documento em um formato e retorna o caminho do arquivo
+type exporter func(d *Document) (string, error)
+
+// exporters mapeia o parâmetro ?format= para o exportador correspondente
+var exporters = map[string]exporter{
+	"markdown": (*Document).exportMarkdown,
+	"epub":     (*Document).ExportEPUB,
+	"html":     (*Document).ExportSite,
+}
+
+// Export gera o documento no formato pedido dentro de output/
+func (d *Document) Export(format string) (string, error) {
+	export, ok := exporters[format]
+	if !ok {
+		return "", fmt.Errorf("%w: %q", ErrUnknownFormat, format)
+	}
+	return export(d)
+}
+
+// exportMarkdown é o SaveDocument visto como exportador
+func (d *Document) exportMarkdown() (string, error) {
+	if err := d.SaveDocument(); err != nil {
+		return "", err
+	}
+	return d.OutputFile(), nil
+}
+
+// exportFormats lista os formatos disponíveis, para mensagens de erro
+func exportFormats() []string {
+	formats := make([]string, 0, len(exporters))
+	for f := range exporters {
+		formats = append(formats, f)
+	}
+	sort.Strings(formats)
+	return formats
+}
+
+// exportFile retorna o caminho em output/ com a extensão do formato
+func (d *Document) exportFile(ext string) string {
+	base := strings.TrimSuffix(filepath.Base(d.OutputPath), filepath.Ext(d.OutputPath))
+	return filepath.Join(d.Dir, outputDir, base+ext)
+}
+
+// sortedSections retorna uma cópia das seções ordenada por Order
+func (d *Document) sortedSections() []TextSection {
+	sections := make([]TextSection, len(d.Sections))
+	copy(sections, d.Sections)
+	sort.SliceStable(sections, func(i, j int) bool {
+		return sections[i].Order < sections[j].Order
+	})
+	return sections
+}
+
+// CoverFile retorna o caminho da capa do documento, ou "" se não houver
+func (d *Document) CoverFile() string {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+	return d.coverFile()
+}
+
+// coverFile procura a capa do documento. Retorna "" se não houver.
+func (d *Document) coverFile() string {
+	for _, ext := range coverTypes {
+		path := filepath.Join(d.Dir, coverName+ext)
+		if _, err := os.Stat(path); err == nil {
+			return path
+		}
+	}
+	return ""
+}
+
+// SetCover grava a imagem de capa do documento, substituindo a anterior
+func (d *Document) SetCover(data []byte) error {
+	ext, ok := coverTypes[http.DetectContentType(data)]
+	if !ok {
+		return ErrInvalidCover
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	path := filepath.Join(d.Dir, coverName+ext)
+	if err := writeFileAtomic(path, data, 0644); err != nil {
+		return fmt.Errorf("erro ao salvar capa: %v", err)
+	}
+	for _, other := range coverTypes {
+		if other != ext {
+			os.Remove(filepath.Join(d.Dir, coverName+other))
+		}
+	}
+	return nil
+}
+
+// handleExport gera o documento no formato de ?format=
+func handleExport(doc *Document) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			w.Header().Set("Allow", "POST")
+			http.Error(w, "método não permitido", http.StatusMethodNotAllowed)
+			return
+		}
+
+		format := r.URL.Query().Get("format")
+		if _, ok := exporters[format]; !ok {
+			http.Error(w, fmt.Sprintf("format deve ser um de: %s",
+				strings.Join(exportFormats(), ", ")), http.StatusBadRequest)
+			return
+		}
+
+		path, err := doc.Export(format)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		size, err := dirSize(path)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		writeJSON(w, http.StatusOK, buildResponse{
+			Path:     doc.workspacePath(path),
+			Sections: doc.Info().Sections,
+			Bytes:    int(size),
+		})
+	}
+}
+
+// handleCover serve (GET) ou substitui (PUT) a capa do documento. O corpo
+// do PUT é a própria imagem.
+func handleCover(doc *Document) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodGet:
+			path := doc.CoverFile()
+			if path == "" {
+				http.Error(w, "documento sem capa", http.StatusNotFound)
+				return
+			}
+			http.ServeFile(w, r, path)
+
+		case http.MethodPut:
+			data, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxCoverBytes))
+			if err != nil {
+				http.Error(w, fmt.Sprintf("capa inválida: %v", err), http.StatusRequestEntityTooLarge)
+				return
+			}
+			if err := doc.SetCover(data); err != nil {
+				status := http.StatusInternalServerError
+				if errors.Is(err, ErrInvalidCover) {
+					status = http.StatusUnsupportedMediaType
+				}
+				http.Error(w, err.Error(), status)
+				return
+			}
+			w.WriteHeader(http.StatusNoContent)
+
+		default:
+			w.Header().Set("Allow", "GET, PUT")
+			http.Error(w, "método não permitido", http.StatusMethodNotAllowed)
+		}
+	}
+}
@@ -2,4 +2,22 @@ module github.com/osdeving/go-writer
 
 go 1.23.5
 
-require github.com/google/uuid v1.6.0 // indirect
+require (
+	github.com/alecthomas/chroma/v2 v2.14.0
+	github.com/google/uuid v1.6.0
+	github.com/microcosm-cc/bluemonday v1.0.27
+	github.com/yuin/goldmark v1.7.8
+	github.com/yuin/goldmark-emoji v1.0.5
+	github.com/yuin/goldmark-highlighting/v2 v2.0.0-20230729083705-37449abec8cc
+	golang.org/x/crypto v0.31.0
+	golang.org/x/net v0.26.0
+	golang.org/x/term v0.27.0
+	golang.org/x/text v0.21.0
+)
+
+require (
+	github.com/aymerick/douceur v0.2.0 // indirect
+	github.com/dlclark/regexp2 v1.11.0 // indirect
+	github.com/gorilla/css v1.0.1 // indirect
+	golang.org/x/sys v0.28.0 // indirect
+)
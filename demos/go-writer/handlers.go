@@ -0,0 +1,554 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"path"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// documentRequest é o corpo JSON aceito na criação e renomeação de documentos
+type documentRequest struct {
+	Title string `json:"title"`
+}
+
+// handleDocuments atende a coleção /api/documents (listar e criar)
+func handleDocuments(ws *Workspace) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodGet:
+			docs := ws.List()
+			infos := make([]DocumentInfo, len(docs))
+			for i, doc := range docs {
+				infos[i] = doc.Info()
+			}
+			writeJSON(w, http.StatusOK, infos)
+
+		case http.MethodPost:
+			var req documentRequest
+			if !decodeJSON(w, r, &req) {
+				return
+			}
+
+			doc, err := ws.Create(req.Title)
+			if err != nil {
+				writeSectionError(w, err)
+				return
+			}
+			w.Header().Set("Location", "/api/documents/"+doc.ID)
+			writeJSON(w, http.StatusCreated, doc.Info())
+
+		default:
+			w.Header().Set("Allow", "GET, POST")
+			http.Error(w, "método não permitido", http.StatusMethodNotAllowed)
+		}
+	}
+}
+
+// handleDocument atende um documento individual em /api/documents/{docID}
+func handleDocument(ws *Workspace) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		id := r.PathValue("docID")
+
+		switch r.Method {
+		case http.MethodGet:
+			doc, err := ws.Get(id)
+			if err != nil {
+				writeSectionError(w, err)
+				return
+			}
+			writeJSON(w, http.StatusOK, doc.Info())
+
+		case http.MethodPut, http.MethodPatch:
+			var req documentRequest
+			if !decodeJSON(w, r, &req) {
+				return
+			}
+
+			doc, err := ws.Rename(id, req.Title)
+			if err != nil {
+				writeSectionError(w, err)
+				return
+			}
+			writeJSON(w, http.StatusOK, doc.Info())
+
+		case http.MethodDelete:
+			if err := ws.Delete(id); err != nil {
+				writeSectionError(w, err)
+				return
+			}
+			w.WriteHeader(http.StatusNoContent)
+
+		default:
+			w.Header().Set("Allow", "GET, PUT, PATCH, DELETE")
+			http.Error(w, "método não permitido", http.StatusMethodNotAllowed)
+		}
+	}
+}
+
+// withDocument resolve o {docID} da rota e entrega o documento ao handler
+func withDocument(ws *Workspace, handler func(*Document) http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		doc, err := ws.Get(r.PathValue("docID"))
+		if err != nil {
+			writeSectionError(w, err)
+			return
+		}
+		handler(doc)(w, r)
+	}
+}
+
+// sectionRequest é o corpo JSON aceito na criação e edição de seções.
+// Campos nulos são ignorados em PATCH.
+type sectionRequest struct {
+	Title    *string `json:"title"`
+	Content  *string `json:"content"`
+	ParentID string  `json:"parentID"` // só na criação; depois use .../move
+}
+
+// savedSection é a resposta de criação e edição: a seção gravada e os
+// avisos de estilo do lint, para o editor exibir junto ao texto
+type savedSection struct {
+	*TextSection
+	Warnings []LintWarning
+}
+
+// writeSaved responde com a seção gravada e seus avisos de lint
+func writeSaved(w http.ResponseWriter, doc *Document, status int, section *TextSection) {
+	// erro só se outra requisição excluiu a seção logo depois de salvar
+	warnings, _ := doc.LintSection(section.ID)
+	if warnings == nil {
+		warnings = []LintWarning{}
+	}
+	w.Header().Set("ETag", sectionETag(section))
+	writeJSON(w, status, savedSection{TextSection: section, Warnings: warnings})
+}
+
+// handleSections atende a coleção .../sections (listar e criar)
+func handleSections(doc *Document) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodGet:
+			writeJSON(w, http.StatusOK, doc.ListSections())
+
+		case http.MethodPost:
+			var req sectionRequest
+			if !decodeJSON(w, r, &req) {
+				return
+			}
+			if req.Title == nil || req.Content == nil {
+				http.Error(w, "título e conteúdo são obrigatórios", http.StatusBadRequest)
+				return
+			}
+
+			section, err := doc.AddSection(*req.Title, *req.Content, req.ParentID, requestAuthor(r))
+			if err != nil {
+				writeSectionError(w, err)
+				return
+			}
+			w.Header().Set("Location", r.URL.Path+"/"+section.ID)
+			writeSaved(w, doc, http.StatusCreated, section)
+
+		default:
+			w.Header().Set("Allow", "GET, POST")
+			http.Error(w, "método não permitido", http.StatusMethodNotAllowed)
+		}
+	}
+}
+
+// handleSection atende uma seção individual em .../sections/{id}
+func handleSection(doc *Document) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		id := r.PathValue("id")
+
+		switch r.Method {
+		case http.MethodGet:
+			section, err := doc.GetSection(id)
+			if err != nil {
+				writeSectionError(w, err)
+				return
+			}
+			w.Header().Set("ETag", sectionETag(section))
+			writeJSON(w, http.StatusOK, section)
+
+		case http.MethodPut, http.MethodPatch:
+			version, ok := requireIfMatch(w, r)
+			if !ok {
+				return
+			}
+
+			var req sectionRequest
+			if !decodeJSON(w, r, &req) {
+				return
+			}
+			// PUT substitui a seção inteira, então exige todos os campos
+			if r.Method == http.MethodPut && (req.Title == nil || req.Content == nil) {
+				http.Error(w, "título e conteúdo são obrigatórios", http.StatusBadRequest)
+				return
+			}
+
+			section, err := doc.UpdateSection(id, req.Title, req.Content, requestAuthor(r), version)
+			if err != nil {
+				writeSectionError(w, err)
+				return
+			}
+			writeSaved(w, doc, http.StatusOK, section)
+
+		case http.MethodDelete:
+			version, ok := requireIfMatch(w, r)
+			if !ok {
+				return
+			}
+			if err := doc.DeleteSection(id, version, requestAuthor(r)); err != nil {
+				writeSectionError(w, err)
+				return
+			}
+			w.WriteHeader(http.StatusNoContent)
+
+		default:
+			w.Header().Set("Allow", "GET, PUT, PATCH, DELETE")
+			http.Error(w, "método não permitido", http.StatusMethodNotAllowed)
+		}
+	}
+}
+
+// reorderRequest é o corpo de POST .../sections/reorder
+type reorderRequest struct {
+	Order []string `json:"order"`
+}
+
+// handleReorder aplica a ordem definida pelo drag-and-drop da interface
+func handleReorder(doc *Document) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			w.Header().Set("Allow", "POST")
+			http.Error(w, "método não permitido", http.StatusMethodNotAllowed)
+			return
+		}
+
+		var req reorderRequest
+		if !decodeJSON(w, r, &req) {
+			return
+		}
+
+		if err := doc.ReorderSections(req.Order); err != nil {
+			// um ID desconhecido aqui é erro do cliente, não recurso ausente
+			if errors.Is(err, ErrSectionNotFound) {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+			writeSectionError(w, err)
+			return
+		}
+		writeJSON(w, http.StatusOK, doc.ListSections())
+	}
+}
+
+// moveRequest é o corpo de POST .../sections/{id}/move
+type moveRequest struct {
+	ParentID string `json:"parentID"`
+	Index    int    `json:"index"`
+}
+
+// handleMove move uma seção, com suas subseções, para outro pai ou outra
+// posição entre os irmãos
+func handleMove(doc *Document) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			w.Header().Set("Allow", "POST")
+			http.Error(w, "método não permitido", http.StatusMethodNotAllowed)
+			return
+		}
+
+		var req moveRequest
+		if !decodeJSON(w, r, &req) {
+			return
+		}
+
+		if err := doc.MoveSection(r.PathValue("id"), req.ParentID, req.Index); err != nil {
+			writeSectionError(w, err)
+			return
+		}
+		writeJSON(w, http.StatusOK, doc.ListSections())
+	}
+}
+
+// buildResponse descreve o arquivo gerado por POST .../build. Path é
+// relativo à raiz do workspace: o caminho absoluto no servidor não
+// interessa ao cliente.
+type buildResponse struct {
+	Path     string `json:"path"`
+	Sections int    `json:"sections"`
+	Bytes    int    `json:"bytes"`
+}
+
+// workspacePath retorna p, um caminho dentro do diretório do documento,
+// relativo à raiz do workspace e com barras (documents/<id>/output/...)
+func (d *Document) workspacePath(p string) string {
+	rel, err := filepath.Rel(d.Dir, p)
+	if err != nil {
+		rel = filepath.Base(p)
+	}
+	return path.Join(documentsDir, d.ID, filepath.ToSlash(rel))
+}
+
+// handleBuild gera o Markdown final do documento
+func handleBuild(doc *Document) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			w.Header().Set("Allow", "POST")
+			http.Error(w, "método não permitido", http.StatusMethodNotAllowed)
+			return
+		}
+
+		if err := doc.SaveDocument(); err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		info, err := os.Stat(doc.OutputFile())
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		writeJSON(w, http.StatusOK, buildResponse{
+			Path:     doc.workspacePath(doc.OutputFile()),
+			Sections: doc.Info().Sections,
+			Bytes:    int(info.Size()),
+		})
+	}
+}
+
+// handleRevisions lista o histórico de uma seção, sem o conteúdo
+func handleRevisions(doc *Document) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			w.Header().Set("Allow", "GET")
+			http.Error(w, "método não permitido", http.StatusMethodNotAllowed)
+			return
+		}
+
+		id := r.PathValue("id")
+		if _, err := doc.GetSection(id); err != nil {
+			writeSectionError(w, err)
+			return
+		}
+
+		revisions, err := doc.Revisions(id)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		for i := range revisions {
+			revisions[i].Content = ""
+		}
+		if revisions == nil {
+			revisions = []Revision{}
+		}
+		writeJSON(w, http.StatusOK, revisions)
+	}
+}
+
+// handleRevision retorna uma revisão completa, com conteúdo
+func handleRevision(doc *Document) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			w.Header().Set("Allow", "GET")
+			http.Error(w, "método não permitido", http.StatusMethodNotAllowed)
+			return
+		}
+
+		number, ok := revisionNumber(w, r.PathValue("rev"))
+		if !ok {
+			return
+		}
+
+		rev, err := doc.Revision(r.PathValue("id"), number)
+		if err != nil {
+			writeSectionError(w, err)
+			return
+		}
+		writeJSON(w, http.StatusOK, rev)
+	}
+}
+
+// handleRestore restaura a seção para uma revisão anterior
+func handleRestore(doc *Document) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			w.Header().Set("Allow", "POST")
+			http.Error(w, "método não permitido", http.StatusMethodNotAllowed)
+			return
+		}
+
+		number, ok := revisionNumber(w, r.PathValue("rev"))
+		if !ok {
+			return
+		}
+		version, ok := requireIfMatch(w, r)
+		if !ok {
+			return
+		}
+
+		section, err := doc.RestoreRevision(r.PathValue("id"), number, requestAuthor(r), version)
+		if err != nil {
+			writeSectionError(w, err)
+			return
+		}
+		w.Header().Set("ETag", sectionETag(section))
+		writeJSON(w, http.StatusOK, section)
+	}
+}
+
+// handleDiff retorna o diff unificado entre as revisões ?from= e ?to=.
+// Sem ?to=, compara com a revisão mais recente.
+func handleDiff(doc *Document) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			w.Header().Set("Allow", "GET")
+			http.Error(w, "método não permitido", http.StatusMethodNotAllowed)
+			return
+		}
+
+		id := r.PathValue("id")
+		from, ok := revisionNumber(w, r.URL.Query().Get("from"))
+		if !ok {
+			return
+		}
+
+		var to int
+		if q := r.URL.Query().Get("to"); q != "" {
+			if to, ok = revisionNumber(w, q); !ok {
+				return
+			}
+		} else {
+			revisions, err := doc.Revisions(id)
+			if err != nil {
+				writeSectionError(w, err)
+				return
+			}
+			if len(revisions) == 0 {
+				writeSectionError(w, fmt.Errorf("%w: %s", ErrRevisionNotFound, id))
+				return
+			}
+			to = revisions[len(revisions)-1].Number
+		}
+
+		diff, err := doc.DiffRevisions(id, from, to)
+		if err != nil {
+			writeSectionError(w, err)
+			return
+		}
+		w.Header().Set("Content-Type", "text/x-diff; charset=utf-8")
+		w.Write([]byte(diff))
+	}
+}
+
+// revisionNumber converte o número da revisão, respondendo 400 se inválido
+func revisionNumber(w http.ResponseWriter, s string) (int, bool) {
+	n, err := strconv.Atoi(s)
+	if err != nil || n < 1 {
+		http.Error(w, fmt.Sprintf("número de revisão inválido: %q", s), http.StatusBadRequest)
+		return 0, false
+	}
+	return n, true
+}
+
+// sectionETag representa a versão da seção como ETag forte
+func sectionETag(s *TextSection) string {
+	return strconv.Quote(strconv.Itoa(s.Version))
+}
+
+// requireIfMatch é ifMatchVersion para alterações de seção: sem o
+// cabeçalho responde 428, para que ninguém sobrescreva ou exclua sem
+// saber o que outra pessoa salvou. "*" continua dispensando a verificação.
+func requireIfMatch(w http.ResponseWriter, r *http.Request) (int, bool) {
+	if r.Header.Get("If-Match") == "" {
+		http.Error(w, "cabeçalho If-Match é obrigatório", http.StatusPreconditionRequired)
+		return 0, false
+	}
+	return ifMatchVersion(w, r)
+}
+
+// ifMatchVersion extrai a versão esperada do cabeçalho If-Match. Sem o
+// cabeçalho, ou com "*", retorna 0, que dispensa a verificação. If-Match
+// usa comparação forte (RFC 9110, seção 13.1.1): uma ETag fraca nunca
+// confere, então a resposta é 412.
+func ifMatchVersion(w http.ResponseWriter, r *http.Request) (int, bool) {
+	value := strings.TrimSpace(r.Header.Get("If-Match"))
+	if value == "" || value == "*" {
+		return 0, true
+	}
+	if strings.HasPrefix(value, "W/") {
+		http.Error(w, fmt.Sprintf("If-Match não aceita ETag fraca: %s", value), http.StatusPreconditionFailed)
+		return 0, false
+	}
+
+	unquoted, err := strconv.Unquote(value)
+	if err == nil {
+		var version int
+		if version, err = strconv.Atoi(unquoted); err == nil && version > 0 {
+			return version, true
+		}
+	}
+	http.Error(w, fmt.Sprintf("If-Match inválido: %s", value), http.StatusBadRequest)
+	return 0, false
+}
+
+// requestAuthor identifica quem fez a alteração: o usuário da sessão
+func requestAuthor(r *http.Request) string {
+	u, _ := requestUser(r)
+	return u.Username
+}
+
+// maxJSONBytes limita o corpo JSON das requisições, com folga para o
+// conteúdo de um capítulo inteiro
+const maxJSONBytes = 4 << 20
+
+// decodeJSON lê o corpo da requisição em v, respondendo 400 em caso de
+// erro e 413 se o corpo passar de maxJSONBytes
+func decodeJSON(w http.ResponseWriter, r *http.Request, v any) bool {
+	dec := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxJSONBytes))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(v); err != nil {
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			http.Error(w, fmt.Sprintf("corpo maior que %d bytes", tooLarge.Limit), http.StatusRequestEntityTooLarge)
+			return false
+		}
+		http.Error(w, fmt.Sprintf("JSON inválido: %v", err), http.StatusBadRequest)
+		return false
+	}
+	return true
+}
+
+// writeJSON serializa v como resposta JSON com o status informado
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
+
+// writeSectionError traduz erros do Document e do Workspace para o
+// status HTTP adequado. Em conflito de versão, devolve a cópia do
+// servidor para o editor decidir o que fazer.
+func writeSectionError(w http.ResponseWriter, err error) {
+	var conflict *ConflictError
+	switch {
+	case errors.As(err, &conflict):
+		w.Header().Set("ETag", sectionETag(&conflict.Current))
+		writeJSON(w, http.StatusConflict, conflict.Current)
+	case errors.Is(err, ErrSectionNotFound), errors.Is(err, ErrDocumentNotFound),
+		errors.Is(err, ErrRevisionNotFound), errors.Is(err, ErrTrashItemNotFound):
+		http.Error(w, err.Error(), http.StatusNotFound)
+	case errors.Is(err, ErrInvalidSection):
+		http.Error(w, err.Error(), http.StatusBadRequest)
+	default:
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
@@ -0,0 +1,298 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+// testPassword é a senha de todos os usuários do servidor de teste
+const testPassword = "senha-de-teste"
+
+// testServer é o servidor completo, com login e CSRF, sobre um workspace
+// temporário com um documento e um usuário por papel (reader, author e
+// editor, com o nome igual ao papel)
+type testServer struct {
+	*httptest.Server
+	ws   *Workspace
+	doc  *Document
+	auth *Auth
+}
+
+func newTestServer(t *testing.T) *testServer {
+	t.Helper()
+	root := t.TempDir()
+
+	ws := NewWorkspace(root)
+	if err := initializeWorkspace(ws); err != nil {
+		t.Fatal(err)
+	}
+	doc, err := ws.Create("Teste")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	users, err := LoadUsers(root)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// custo mínimo: o custo padrão do bcrypt deixaria os testes lentos
+	hash, err := bcrypt.GenerateFromPassword([]byte(testPassword), bcrypt.MinCost)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, role := range []Role{RoleReader, RoleAuthor, RoleEditor} {
+		users.users[string(role)] = User{Username: string(role), PasswordHash: string(hash), Role: role}
+	}
+	if err := users.save(); err != nil {
+		t.Fatal(err)
+	}
+
+	auth := NewAuth(users, root)
+	srv := httptest.NewServer(auth.Middleware(routes(ws, auth)))
+	t.Cleanup(srv.Close)
+	return &testServer{Server: srv, ws: ws, doc: doc, auth: auth}
+}
+
+// docPath monta o caminho da API do documento de teste
+func (ts *testServer) docPath(parts ...string) string {
+	return "/api/documents/" + ts.doc.ID + "/" + strings.Join(parts, "/")
+}
+
+// testClient é um navegador logado: envia o cookie da sessão e o token
+// CSRF em toda requisição
+type testClient struct {
+	t      *testing.T
+	ts     *testServer
+	cookie *http.Cookie
+	csrf   string
+}
+
+// login entra como username pelo formulário de /login
+func (ts *testServer) login(t *testing.T, username string) *testClient {
+	t.Helper()
+	client := &http.Client{
+		CheckRedirect: func(*http.Request, []*http.Request) error { return http.ErrUseLastResponse },
+	}
+	resp, err := client.PostForm(ts.URL+"/login", url.Values{
+		"username": {username},
+		"password": {testPassword},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusSeeOther {
+		t.Fatalf("login de %s: status %d", username, resp.StatusCode)
+	}
+
+	c := &testClient{t: t, ts: ts}
+	for _, cookie := range resp.Cookies() {
+		if cookie.Name == sessionCookie {
+			c.cookie = cookie
+		}
+	}
+	if c.cookie == nil {
+		t.Fatalf("login de %s sem cookie de sessão", username)
+	}
+
+	r := httptest.NewRequest(http.MethodGet, "/", nil)
+	r.AddCookie(c.cookie)
+	c.csrf = ts.auth.session(r).CSRF
+	return c
+}
+
+// do envia a requisição com body serializado em JSON (se não for nil) e
+// os cabeçalhos extras em pares nome, valor
+func (c *testClient) do(method, path string, body any, header ...string) *http.Response {
+	c.t.Helper()
+	var reader io.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			c.t.Fatal(err)
+		}
+		reader = bytes.NewReader(data)
+	}
+
+	req, err := http.NewRequest(method, c.ts.URL+path, reader)
+	if err != nil {
+		c.t.Fatal(err)
+	}
+	req.AddCookie(c.cookie)
+	req.Header.Set(csrfHeader, c.csrf)
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	for i := 0; i+1 < len(header); i += 2 {
+		req.Header.Set(header[i], header[i+1])
+	}
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		c.t.Fatal(err)
+	}
+	c.t.Cleanup(func() { resp.Body.Close() })
+	return resp
+}
+
+// expect envia a requisição e falha o teste se o status for outro
+func (c *testClient) expect(status int, method, path string, body any, header ...string) *http.Response {
+	c.t.Helper()
+	resp := c.do(method, path, body, header...)
+	if resp.StatusCode != status {
+		data, _ := io.ReadAll(resp.Body)
+		c.t.Fatalf("%s %s: status %d, esperado %d: %s", method, path, resp.StatusCode, status, data)
+	}
+	return resp
+}
+
+// decode lê a resposta JSON em v
+func decode(t *testing.T, resp *http.Response, v any) {
+	t.Helper()
+	if err := json.NewDecoder(resp.Body).Decode(v); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// addSection cria uma seção pela API e devolve o que o servidor gravou
+func (c *testClient) addSection(title, content string) TextSection {
+	c.t.Helper()
+	resp := c.expect(http.StatusCreated, http.MethodPost, c.ts.docPath("sections"),
+		sectionRequest{Title: &title, Content: &content})
+	var s TextSection
+	decode(c.t, resp, &s)
+	return s
+}
+
+func TestSectionChangesRequireIfMatch(t *testing.T) {
+	ts := newTestServer(t)
+	editor := ts.login(t, "editor")
+	section := editor.addSection("Intro", "v1")
+	path := ts.docPath("sections", section.ID)
+	title, content := "Intro", "v2"
+	body := sectionRequest{Title: &title, Content: &content}
+
+	editor.expect(http.StatusPreconditionRequired, http.MethodPut, path, body)
+	editor.expect(http.StatusPreconditionRequired, http.MethodPatch, path, body)
+	editor.expect(http.StatusPreconditionRequired, http.MethodDelete, path, nil)
+	editor.expect(http.StatusPreconditionRequired, http.MethodPost, path+"/revisions/1/restore", nil)
+
+	if s, err := ts.doc.GetSection(section.ID); err != nil || s.Version != 1 {
+		t.Fatalf("seção alterada sem If-Match: %+v, %v", s, err)
+	}
+}
+
+func TestSectionChangesRejectWeakETag(t *testing.T) {
+	ts := newTestServer(t)
+	editor := ts.login(t, "editor")
+	section := editor.addSection("Intro", "v1")
+	path := ts.docPath("sections", section.ID)
+	content := "v2"
+
+	// If-Match exige comparação forte: W/"1" não confere nem com a versão 1
+	editor.expect(http.StatusPreconditionFailed, http.MethodPatch, path,
+		sectionRequest{Content: &content}, "If-Match", `W/"1"`)
+	editor.expect(http.StatusPreconditionFailed, http.MethodDelete, path, nil, "If-Match", `W/"1"`)
+	editor.expect(http.StatusPreconditionFailed, http.MethodPost, path+"/revisions/1/restore", nil, "If-Match", `W/"1"`)
+
+	if s, err := ts.doc.GetSection(section.ID); err != nil || s.Version != 1 {
+		t.Fatalf("seção alterada com ETag fraca: %+v, %v", s, err)
+	}
+}
+
+func TestSectionChangesConflict(t *testing.T) {
+	ts := newTestServer(t)
+	editor := ts.login(t, "editor")
+	section := editor.addSection("Intro", "v1")
+	path := ts.docPath("sections", section.ID)
+	title := "Intro"
+
+	// a primeira gravação com a versão lida vence
+	content := "v2"
+	resp := editor.expect(http.StatusOK, http.MethodPut, path,
+		sectionRequest{Title: &title, Content: &content}, "If-Match", `"1"`)
+	if etag := resp.Header.Get("ETag"); etag != `"2"` {
+		t.Errorf("ETag = %s, esperado \"2\"", etag)
+	}
+
+	// quem ainda está na versão 1 recebe a cópia do servidor
+	stale := "v2 de outra pessoa"
+	for _, req := range []struct {
+		method, path string
+		body         any
+	}{
+		{http.MethodPut, path, sectionRequest{Title: &title, Content: &stale}},
+		{http.MethodPatch, path, sectionRequest{Content: &stale}},
+		{http.MethodDelete, path, nil},
+		{http.MethodPost, path + "/revisions/1/restore", nil},
+	} {
+		resp := editor.expect(http.StatusConflict, req.method, req.path, req.body, "If-Match", `"1"`)
+		if etag := resp.Header.Get("ETag"); etag != `"2"` {
+			t.Errorf("%s: ETag = %s, esperado \"2\"", req.method, etag)
+		}
+		var current TextSection
+		decode(t, resp, &current)
+		if current.Content != "v2" || current.Version != 2 {
+			t.Errorf("%s: cópia do servidor = %+v", req.method, current)
+		}
+	}
+
+	editor.expect(http.StatusBadRequest, http.MethodDelete, path, nil, "If-Match", "v2")
+	editor.expect(http.StatusOK, http.MethodPost, path+"/revisions/1/restore", nil, "If-Match", `"2"`)
+	editor.expect(http.StatusNoContent, http.MethodDelete, path, nil, "If-Match", `"3"`)
+}
+
+func TestBuildReturnsWorkspacePath(t *testing.T) {
+	ts := newTestServer(t)
+	author := ts.login(t, "author")
+	author.addSection("Intro", "Texto.")
+
+	var got buildResponse
+	decode(t, author.expect(http.StatusOK, http.MethodPost, ts.docPath("build"), nil), &got)
+	want := "documents/" + ts.doc.ID + "/output/" + ts.doc.OutputPath
+	if got.Path != want || strings.Contains(got.Path, ts.ws.Root) {
+		t.Errorf("path = %q, esperado %q", got.Path, want)
+	}
+	if got.Sections != 1 || got.Bytes == 0 {
+		t.Errorf("resposta = %+v", got)
+	}
+}
+
+func TestRequestBodyLimit(t *testing.T) {
+	ts := newTestServer(t)
+	author := ts.login(t, "author")
+
+	title, content := "Grande", strings.Repeat("a", maxJSONBytes)
+	author.expect(http.StatusRequestEntityTooLarge, http.MethodPost, ts.docPath("sections"),
+		sectionRequest{Title: &title, Content: &content})
+	if n := len(ts.doc.ListSections()); n != 0 {
+		t.Errorf("%d seções criadas com corpo acima do limite", n)
+	}
+}
+
+func TestExportReturnsWorkspacePath(t *testing.T) {
+	ts := newTestServer(t)
+	author := ts.login(t, "author")
+	author.addSection("Intro", "Texto.")
+
+	base := strings.TrimSuffix(ts.doc.OutputPath, ".md")
+	for format, name := range map[string]string{
+		"markdown": ts.doc.OutputPath,
+		"epub":     base + ".epub",
+		"html":     siteDir,
+	} {
+		var got buildResponse
+		decode(t, author.expect(http.StatusOK, http.MethodPost, ts.docPath("export")+"?format="+format, nil), &got)
+		if want := "documents/" + ts.doc.ID + "/output/" + name; got.Path != want {
+			t.Errorf("%s: path = %q, esperado %q", format, got.Path, want)
+		}
+	}
+}
@@ -0,0 +1,196 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// summaryLinkRegex encontra links no formato [Título](caminho), o mesmo
+// formato usado pelo sumário do livro (book/go-bible.md). O grupo opcional
+// "!" permite descartar imagens.
+var summaryLinkRegex = regexp.MustCompile(`(!?)\[(?P<title>[^\]]+)\]\((?P<path>[^)]+)\)`)
+
+// importAuthor identifica nas revisões as seções criadas pela importação
+const importAuthor = "importação"
+
+// SummaryLink é uma entrada do sumário. Links apontam para o arquivo da
+// seção; títulos (Level > 0, como "## Parte 1" e "### Capítulo 1")
+// agrupam os links que vêm depois deles.
+type SummaryLink struct {
+	Title string
+	Path  string
+	Level int
+}
+
+// ImportResult resume o que foi criado por ImportSummary. Groups conta as
+// seções criadas a partir dos títulos do sumário; Outside lista os links
+// que saem do diretório do sumário e por isso não foram lidos.
+type ImportResult struct {
+	Imported int
+	Groups   int
+	Missing  []string
+	Outside  []string
+}
+
+// parseSummary extrai, na ordem, os títulos de nível 2 em diante e os
+// links para arquivos locais do sumário. Imagens, links externos e
+// âncoras são ignorados; o título de nível 1 é o do próprio documento.
+func parseSummary(content string) []SummaryLink {
+	var links []SummaryLink
+	for _, line := range strings.Split(content, "\n") {
+		if level, text, ok := parseHeading(line); ok {
+			if level > 1 {
+				title := strings.TrimSpace(strings.ReplaceAll(text, "**", ""))
+				links = append(links, SummaryLink{Title: title, Level: level})
+			}
+			continue
+		}
+		for _, match := range summaryLinkRegex.FindAllStringSubmatch(line, -1) {
+			isImage, title, path := match[1] == "!", match[2], strings.TrimSpace(match[3])
+			if isImage || path == "" || strings.HasPrefix(path, "#") || strings.Contains(path, "://") {
+				continue
+			}
+			links = append(links, SummaryLink{Title: title, Path: path})
+		}
+	}
+	return links
+}
+
+// summaryTitle retorna o texto do primeiro heading do sumário, sem
+// marcações de negrito, para ser usado como título do documento
+func summaryTitle(content string) string {
+	for _, line := range strings.Split(content, "\n") {
+		if _, text, ok := parseHeading(line); ok {
+			return strings.TrimSpace(strings.ReplaceAll(text, "**", ""))
+		}
+	}
+	return ""
+}
+
+// ImportSummary lê um sumário de livro e cria uma seção por link, na
+// ordem em que aparecem, com o conteúdo do arquivo apontado. Os títulos
+// do sumário viram seções vazias que contêm os links abaixo deles (parte
+// → capítulo → seção); títulos sem nenhum link são descartados. Caminhos
+// são relativos ao diretório do sumário e não podem sair dele. Arquivos
+// inexistentes ou fora do diretório viram seções vazias, para que o
+// buraco fique visível no editor.
+func (d *Document) ImportSummary(summaryPath string) (*ImportResult, error) {
+	data, err := os.ReadFile(summaryPath)
+	if err != nil {
+		return nil, fmt.Errorf("erro ao ler sumário %s: %v", summaryPath, err)
+	}
+
+	baseDir := filepath.Dir(summaryPath)
+	result := &ImportResult{}
+
+	// títulos abertos, do mais externo ao mais interno; ID vazio enquanto
+	// nenhum link abaixo deles tiver aparecido
+	type group struct {
+		level int
+		title string
+		id    string
+	}
+	var open []group
+
+	for _, link := range parseSummary(string(data)) {
+		if link.Level > 0 {
+			for len(open) > 0 && open[len(open)-1].level >= link.Level {
+				open = open[:len(open)-1]
+			}
+			open = append(open, group{level: link.Level, title: link.Title})
+			continue
+		}
+
+		parentID := ""
+		for i := range open {
+			if open[i].id == "" {
+				section, err := d.AddSection(open[i].title, "", parentID, importAuthor)
+				if err != nil {
+					return result, err
+				}
+				open[i].id = section.ID
+				result.Groups++
+			}
+			parentID = open[i].id
+		}
+
+		path := filepath.Join(baseDir, filepath.FromSlash(link.Path))
+
+		var content []byte
+		var err error
+		if insideDir(baseDir, path) {
+			content, err = os.ReadFile(path)
+		} else {
+			result.Outside = append(result.Outside, link.Path)
+		}
+		switch {
+		case err == nil:
+		case os.IsNotExist(err):
+			result.Missing = append(result.Missing, link.Path)
+		default:
+			return result, fmt.Errorf("erro ao ler seção %s: %v", path, err)
+		}
+
+		if _, err := d.AddSection(link.Title, string(content), parentID, importAuthor); err != nil {
+			return result, err
+		}
+		result.Imported++
+	}
+	return result, nil
+}
+
+// runImport implementa o comando "go-writer import <sumário.md> [título]",
+// que cria um novo documento no workspace a partir do sumário
+func runImport(ws *Workspace, args []string) error {
+	if len(args) < 1 {
+		return fmt.Errorf("uso: go-writer import <sumário.md> [título]")
+	}
+	summaryPath := args[0]
+
+	// lê antes de criar o documento para não deixar um documento vazio
+	// para trás quando o caminho estiver errado
+	data, err := os.ReadFile(summaryPath)
+	if err != nil {
+		return fmt.Errorf("erro ao ler sumário %s: %v", summaryPath, err)
+	}
+
+	title := summaryTitle(string(data))
+	if len(args) > 1 {
+		title = args[1]
+	}
+	if title == "" {
+		title = defaultTitle
+	}
+
+	doc, err := ws.Create(title)
+	if err != nil {
+		return err
+	}
+
+	result, err := doc.ImportSummary(summaryPath)
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("Documento %q (%s): %d seções importadas em %d partes e capítulos\n",
+		title, doc.ID, result.Imported, result.Groups)
+	for _, missing := range result.Missing {
+		fmt.Printf("Aviso: arquivo não encontrado, seção criada vazia: %s\n", missing)
+	}
+	for _, outside := range result.Outside {
+		fmt.Printf("Aviso: link fora do diretório do sumário, seção criada vazia: %s\n", outside)
+	}
+	return nil
+}
+
+// insideDir indica se path, já limpo por filepath.Join, fica dentro de dir
+func insideDir(dir, path string) bool {
+	rel, err := filepath.Rel(dir, path)
+	if err != nil {
+		return false
+	}
+	return rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator))
+}
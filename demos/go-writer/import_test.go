@@ -0,0 +1,46 @@
+package main
+
+import (
+	"path/filepath"
+	"slices"
+	"testing"
+)
+
+func TestImportSummaryStaysInsideSummaryDir(t *testing.T) {
+	root := t.TempDir()
+	writeFiles(t, root, map[string]string{
+		"segredo.md": "não deveria ser lido\n",
+		"livro/SUMMARY.md": "# Livro\n\n## Parte 1\n\n" +
+			"- [Intro](intro.md)\n" +
+			"- [Fora](../segredo.md)\n" +
+			"- [Escondido](sub/../../segredo.md)\n",
+		"livro/intro.md": "Olá\n",
+	})
+
+	ws := NewWorkspace(root)
+	doc, err := ws.Create("Livro")
+	if err != nil {
+		t.Fatal(err)
+	}
+	result, err := doc.ImportSummary(filepath.Join(root, "livro", "SUMMARY.md"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := []string{"../segredo.md", "sub/../../segredo.md"}
+	if !slices.Equal(result.Outside, want) {
+		t.Errorf("Outside = %q, esperado %q", result.Outside, want)
+	}
+	for _, s := range doc.ListSections() {
+		switch s.Title {
+		case "Intro":
+			if s.Content != "Olá\n" {
+				t.Errorf("Intro = %q", s.Content)
+			}
+		case "Fora", "Escondido":
+			if s.Content != "" {
+				t.Errorf("%s leu arquivo fora do sumário: %q", s.Title, s.Content)
+			}
+		}
+	}
+}
@@ -0,0 +1,315 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
+	"unicode/utf8"
+)
+
+// lintFile guarda a configuração das verificações na raiz do workspace
+const lintFile = "lint.json"
+
+// defaultMaxCodeLine é a largura máxima de uma linha de código, pensada
+// para caber na página impressa e no EPUB sem quebra
+const defaultMaxCodeLine = 80
+
+// codeTabWidth é quantas colunas um tab ocupa ao medir linhas de código
+const codeTabWidth = 4
+
+// LintConfig é o conteúdo de lint.json. Campos ausentes usam os padrões.
+type LintConfig struct {
+	MaxCodeLine int      `json:"maxCodeLine"` // 0 usa defaultMaxCodeLine
+	BannedWords []string `json:"bannedWords"` // palavras ou expressões
+	Disabled    []string `json:"disabled"`    // nomes de regras desligadas
+}
+
+// LintWarning é um problema de estilo encontrado ao salvar uma seção.
+// Avisos não impedem o salvamento; Line começa em 1 e é 0 para o título.
+type LintWarning struct {
+	Rule    string
+	Line    int
+	Message string
+}
+
+// lintRule é uma verificação do pipeline. Novas regras só precisam ser
+// acrescentadas a lintRules.
+type lintRule struct {
+	Name  string
+	Check func(src *lintSource, cfg *LintConfig) []LintWarning
+}
+
+// lintRules são as verificações executadas, na ordem
+var lintRules = []lintRule{
+	{"heading-hierarchy", lintHeadingHierarchy},
+	{"heading-style", lintHeadingStyle},
+	{"duplicate-heading", lintDuplicateHeadings},
+	{"unclosed-fence", lintUnclosedFence},
+	{"code-line-length", lintCodeLineLength},
+	{"banned-word", lintBannedWords},
+}
+
+// LoadLintConfig lê root/lint.json. Um arquivo ausente equivale à
+// configuração padrão.
+func LoadLintConfig(root string) (*LintConfig, error) {
+	path := filepath.Join(root, lintFile)
+	cfg := &LintConfig{}
+
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return cfg, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("erro ao ler %s: %v", path, err)
+	}
+	if err := json.Unmarshal(data, cfg); err != nil {
+		return nil, fmt.Errorf("erro ao ler %s: %v", path, err)
+	}
+
+	for _, name := range cfg.Disabled {
+		if !slices.ContainsFunc(lintRules, func(r lintRule) bool { return r.Name == name }) {
+			return nil, fmt.Errorf("regra desconhecida em %s: %s", path, name)
+		}
+	}
+	return cfg, nil
+}
+
+// lintHeading é um heading ATX do conteúdo
+type lintHeading struct {
+	Line  int
+	Level int
+	Text  string
+}
+
+// lintSource é a seção já dividida em linhas, com os blocos de código e
+// os headings identificados uma única vez para todas as regras
+type lintSource struct {
+	Title     string
+	BaseLevel int // nível do heading do título no documento gerado
+	Lines     []string
+	InCode    []bool // linha dentro de um bloco cercado, sem as cercas
+	Headings  []lintHeading
+	OpenFence int // linha da cerca que nunca foi fechada, ou 0
+}
+
+// newLintSource analisa o conteúdo como markdownHeadings: os blocos de
+// código seguem fenceScanner, e headings dentro deles são texto
+func newLintSource(s *TextSection, depth int) *lintSource {
+	src := &lintSource{
+		Title:     s.Title,
+		BaseLevel: len(headingMarker(depth)),
+		Lines:     strings.Split(s.Content, "\n"),
+	}
+	src.InCode = make([]bool, len(src.Lines))
+
+	var fences fenceScanner
+	for i, line := range src.Lines {
+		switch fences.scan(line) {
+		case codeLine:
+			src.InCode[i] = true
+		case fenceLine:
+			src.OpenFence = 0
+			if fences.inCode() {
+				src.OpenFence = i + 1
+			}
+		case textLine:
+			if level, text, ok := parseHeading(line); ok {
+				src.Headings = append(src.Headings, lintHeading{Line: i + 1, Level: level, Text: text})
+			}
+		}
+	}
+	return src
+}
+
+// lint executa as regras habilitadas e ordena os avisos por linha
+func lint(src *lintSource, cfg *LintConfig) []LintWarning {
+	if cfg == nil {
+		cfg = &LintConfig{}
+	}
+	var warnings []LintWarning
+	for _, rule := range lintRules {
+		if slices.Contains(cfg.Disabled, rule.Name) {
+			continue
+		}
+		for _, w := range rule.Check(src, cfg) {
+			w.Rule = rule.Name
+			warnings = append(warnings, w)
+		}
+	}
+	sort.SliceStable(warnings, func(i, j int) bool {
+		return warnings[i].Line < warnings[j].Line
+	})
+	return warnings
+}
+
+// LintSection verifica a seção com a configuração do workspace. O nível
+// dos headings é avaliado pela posição da seção na árvore.
+func (d *Document) LintSection(id string) ([]LintWarning, error) {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+
+	i := d.indexOf(id)
+	if i < 0 {
+		return nil, fmt.Errorf("%w: %s", ErrSectionNotFound, id)
+	}
+	depth := d.depths()[id]
+	return lint(newLintSource(&d.Sections[i], depth), d.Lint), nil
+}
+
+// lintHeadingHierarchy exige que os headings do conteúdo fiquem abaixo do
+// título da seção e desçam um nível por vez (## seguido de ####, não)
+func lintHeadingHierarchy(src *lintSource, _ *LintConfig) []LintWarning {
+	var warnings []LintWarning
+	prev := src.BaseLevel
+	for _, h := range src.Headings {
+		switch {
+		case h.Level <= src.BaseLevel:
+			warnings = append(warnings, LintWarning{
+				Line: h.Line,
+				Message: fmt.Sprintf("heading de nível %d no mesmo nível ou acima do título da seção (nível %d); use %s",
+					h.Level, src.BaseLevel, strings.Repeat("#", min(src.BaseLevel+1, 6))),
+			})
+		case h.Level > prev+1:
+			warnings = append(warnings, LintWarning{
+				Line:    h.Line,
+				Message: fmt.Sprintf("heading pula do nível %d para o %d", prev, h.Level),
+			})
+		}
+		prev = max(h.Level, src.BaseLevel)
+	}
+	return warnings
+}
+
+// lintHeadingStyle aponta headings inteiros em negrito, como
+// "# **2.1 Título**": o tema já destaca headings
+func lintHeadingStyle(src *lintSource, _ *LintConfig) []LintWarning {
+	var warnings []LintWarning
+	for _, h := range src.Headings {
+		for _, mark := range []string{"**", "__"} {
+			inner, ok := strings.CutPrefix(h.Text, mark)
+			if ok && strings.HasSuffix(inner, mark) && len(inner) > len(mark) {
+				warnings = append(warnings, LintWarning{
+					Line:    h.Line,
+					Message: "heading todo em negrito; remova o " + mark,
+				})
+				break
+			}
+		}
+	}
+	return warnings
+}
+
+// lintDuplicateHeadings aponta headings repetidos na seção ou iguais ao
+// título, que geram âncoras ambíguas no sumário
+func lintDuplicateHeadings(src *lintSource, _ *LintConfig) []LintWarning {
+	var warnings []LintWarning
+	seen := map[string]int{githubAnchor(src.Title): 0}
+	for _, h := range src.Headings {
+		key := githubAnchor(h.Text)
+		if line, ok := seen[key]; ok {
+			where := fmt.Sprintf("na linha %d", line)
+			if line == 0 {
+				where = "no título da seção"
+			}
+			warnings = append(warnings, LintWarning{
+				Line:    h.Line,
+				Message: fmt.Sprintf("heading %q repetido (já usado %s)", h.Text, where),
+			})
+			continue
+		}
+		seen[key] = h.Line
+	}
+	return warnings
+}
+
+// lintUnclosedFence aponta um bloco de código que vai até o fim da seção
+func lintUnclosedFence(src *lintSource, _ *LintConfig) []LintWarning {
+	if src.OpenFence == 0 {
+		return nil
+	}
+	return []LintWarning{{
+		Line:    src.OpenFence,
+		Message: "bloco de código aberto e nunca fechado",
+	}}
+}
+
+// lintCodeLineLength aponta linhas de código mais largas que o limite
+func lintCodeLineLength(src *lintSource, cfg *LintConfig) []LintWarning {
+	limit := cfg.MaxCodeLine
+	if limit <= 0 {
+		limit = defaultMaxCodeLine
+	}
+
+	var warnings []LintWarning
+	for i, line := range src.Lines {
+		if !src.InCode[i] {
+			continue
+		}
+		width := utf8.RuneCountInString(line) + strings.Count(line, "\t")*(codeTabWidth-1)
+		if width > limit {
+			warnings = append(warnings, LintWarning{
+				Line:    i + 1,
+				Message: fmt.Sprintf("linha de código com %d colunas (máximo %d)", width, limit),
+			})
+		}
+	}
+	return warnings
+}
+
+// lintBannedWords procura as palavras proibidas fora dos blocos de
+// código, sem diferenciar maiúsculas nem acentos. Expressões com mais de
+// uma palavra precisam aparecer na mesma linha.
+func lintBannedWords(src *lintSource, cfg *LintConfig) []LintWarning {
+	var banned [][]string
+	for _, w := range cfg.BannedWords {
+		var terms []string
+		for _, tok := range tokenize(w) {
+			terms = append(terms, tok.term)
+		}
+		if len(terms) > 0 {
+			banned = append(banned, terms)
+		}
+	}
+	if len(banned) == 0 {
+		return nil
+	}
+
+	var warnings []LintWarning
+	for i, line := range src.Lines {
+		if src.InCode[i] {
+			continue
+		}
+		tokens := tokenize(line)
+		for start := range tokens {
+			for _, terms := range banned {
+				if !matchTerms(tokens[start:], terms) {
+					continue
+				}
+				end := tokens[start+len(terms)-1].end
+				warnings = append(warnings, LintWarning{
+					Line:    i + 1,
+					Message: fmt.Sprintf("evite %q", line[tokens[start].start:end]),
+				})
+			}
+		}
+	}
+	return warnings
+}
+
+// matchTerms informa se tokens começa com a sequência terms
+func matchTerms(tokens []token, terms []string) bool {
+	if len(tokens) < len(terms) {
+		return false
+	}
+	for i, term := range terms {
+		if tokens[i].term != term {
+			return false
+		}
+	}
+	return true
+}
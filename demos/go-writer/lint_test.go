@@ -0,0 +1,146 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestLintRules(t *testing.T) {
+	tests := []struct {
+		rule    string
+		name    string
+		title   string
+		depth   int
+		content string
+		cfg     LintConfig
+		want    []int // linhas com aviso da regra
+	}{
+		{rule: "heading-hierarchy", name: "abaixo do título", content: "### A\n#### B\n### C"},
+		{rule: "heading-hierarchy", name: "mesmo nível do título", content: "## A", want: []int{1}},
+		{rule: "heading-hierarchy", name: "acima do título", content: "texto\n# A", want: []int{2}},
+		{rule: "heading-hierarchy", name: "pulo de nível", content: "### A\n##### B", want: []int{2}},
+		{rule: "heading-hierarchy", name: "seção aninhada", depth: 2, content: "#### A\n##### B", want: []int{1}},
+		{rule: "heading-hierarchy", name: "dentro de bloco de código", content: "```sh\n# comentário\n```"},
+		{rule: "heading-hierarchy", name: "bloco aninhado em cerca maior", content: "````md\n```go\n# x\n```\n## y\n````\n## z", want: []int{7}},
+
+		{rule: "heading-style", name: "negrito com asteriscos", content: "### **Negrito**", want: []int{1}},
+		{rule: "heading-style", name: "negrito com sublinhados", content: "### __Negrito__", want: []int{1}},
+		{rule: "heading-style", name: "negrito parcial", content: "### **Parte** do título"},
+		{rule: "heading-style", name: "só marcadores", content: "### ****"},
+		{rule: "heading-style", name: "dentro de bloco de código", content: "~~~\n### **x**\n~~~"},
+
+		{rule: "duplicate-heading", name: "igual ao título", title: "Intro", content: "### Intro", want: []int{1}},
+		{rule: "duplicate-heading", name: "repetido na seção", title: "T", content: "### A\n### B\n#### a", want: []int{3}},
+		{rule: "duplicate-heading", name: "distintos", title: "T", content: "### A\n### B"},
+		{rule: "duplicate-heading", name: "dentro de bloco de código", title: "Intro", content: "```\n### Intro\n```"},
+
+		{rule: "unclosed-fence", name: "fechado", content: "```go\nx\n```"},
+		{rule: "unclosed-fence", name: "aberto", content: "texto\n```go\nx", want: []int{2}},
+		{rule: "unclosed-fence", name: "cerca com info string não fecha", content: "```\nx\n```go", want: []int{1}},
+		{rule: "unclosed-fence", name: "cerca menor não fecha", content: "````\nx\n```", want: []int{1}},
+		{rule: "unclosed-fence", name: "cerca maior fecha", content: "```\nx\n`````"},
+		{rule: "unclosed-fence", name: "outro caractere não fecha", content: "~~~\nx\n```", want: []int{1}},
+		{rule: "unclosed-fence", name: "bloco aninhado", content: "````md\n```go\nx\n```\n````"},
+		{rule: "unclosed-fence", name: "segundo bloco aberto", content: "```\na\n```\n\n```\nb", want: []int{5}},
+		{rule: "unclosed-fence", name: "código inline não é cerca", content: "```x``` e ``y``"},
+
+		{rule: "code-line-length", name: "no limite", content: "```\n1234567890\n```", cfg: LintConfig{MaxCodeLine: 10}},
+		{rule: "code-line-length", name: "acima do limite", content: "```\n12345678901\n```", cfg: LintConfig{MaxCodeLine: 10}, want: []int{2}},
+		{rule: "code-line-length", name: "tab conta como quatro colunas", content: "```\n\t1234567\n```", cfg: LintConfig{MaxCodeLine: 10}, want: []int{2}},
+		{rule: "code-line-length", name: "acentos contam como um caractere", content: "```\nçãçãçãçãçã\n```", cfg: LintConfig{MaxCodeLine: 10}},
+		{rule: "code-line-length", name: "fora de blocos de código", content: strings.Repeat("texto ", 20)},
+		{rule: "code-line-length", name: "a cerca não é código", content: "```" + strings.Repeat("x", 100) + "\n```"},
+		{rule: "code-line-length", name: "limite padrão", content: "```\n" + strings.Repeat("x", defaultMaxCodeLine) + "\n" + strings.Repeat("x", defaultMaxCodeLine+1) + "\n```", want: []int{3}},
+		{rule: "code-line-length", name: "cerca interna é código", content: "````\n```" + strings.Repeat("x", 20) + "\n````", cfg: LintConfig{MaxCodeLine: 10}, want: []int{2}},
+
+		{rule: "banned-word", name: "sem lista", content: "basicamente"},
+		{rule: "banned-word", name: "palavra", content: "ok\nIsso é Basicamente isso", cfg: LintConfig{BannedWords: []string{"basicamente"}}, want: []int{2}},
+		{rule: "banned-word", name: "sem acentos", content: "É BASICO", cfg: LintConfig{BannedWords: []string{"básico"}}, want: []int{1}},
+		{rule: "banned-word", name: "expressão", content: "De  fato, sim", cfg: LintConfig{BannedWords: []string{"de fato"}}, want: []int{1}},
+		{rule: "banned-word", name: "expressão quebrada entre linhas", content: "de\nfato", cfg: LintConfig{BannedWords: []string{"de fato"}}},
+		{rule: "banned-word", name: "parte de outra palavra", content: "basicamentes", cfg: LintConfig{BannedWords: []string{"basicamente"}}},
+		{rule: "banned-word", name: "dentro de bloco de código", content: "```\nbasicamente\n```", cfg: LintConfig{BannedWords: []string{"basicamente"}}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.rule+"/"+tt.name, func(t *testing.T) {
+			src := newLintSource(&TextSection{Title: tt.title, Content: tt.content}, tt.depth)
+			var got []int
+			for _, w := range lint(src, &tt.cfg) {
+				if w.Rule == tt.rule {
+					got = append(got, w.Line)
+				}
+			}
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("avisos nas linhas %v, esperado %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLintOrderAndDisabled(t *testing.T) {
+	content := "```\nbasicamente\n\n## **Intro**\nbasicamente"
+	src := newLintSource(&TextSection{Title: "Intro", Content: content}, 0)
+	cfg := &LintConfig{BannedWords: []string{"basicamente"}}
+
+	var got []string
+	for _, w := range lint(src, cfg) {
+		got = append(got, w.Rule)
+	}
+	want := []string{"unclosed-fence"}
+	if !slices.Equal(got, want) {
+		t.Errorf("bloco aberto até o fim: regras %q, esperado %q", got, want)
+	}
+
+	src = newLintSource(&TextSection{Title: "Intro", Content: "basicamente\n## **Intro**"}, 0)
+	got = nil
+	for _, w := range lint(src, cfg) {
+		got = append(got, w.Rule)
+	}
+	want = []string{"banned-word", "heading-hierarchy", "heading-style", "duplicate-heading"}
+	if !slices.Equal(got, want) {
+		t.Errorf("regras %q, esperado %q (ordem por linha, depois pelo pipeline)", got, want)
+	}
+
+	cfg.Disabled = []string{"heading-style", "banned-word"}
+	got = nil
+	for _, w := range lint(src, cfg) {
+		got = append(got, w.Rule)
+	}
+	want = []string{"heading-hierarchy", "duplicate-heading"}
+	if !slices.Equal(got, want) {
+		t.Errorf("com regras desligadas: %q, esperado %q", got, want)
+	}
+}
+
+func TestLoadLintConfig(t *testing.T) {
+	root := t.TempDir()
+	cfg, err := LoadLintConfig(root)
+	if err != nil || cfg.MaxCodeLine != 0 || cfg.BannedWords != nil || cfg.Disabled != nil {
+		t.Fatalf("sem lint.json: %+v, %v", cfg, err)
+	}
+
+	writeFiles(t, root, map[string]string{lintFile: `{"maxCodeLine": 60, "bannedWords": ["óbvio"], "disabled": ["heading-style"]}`})
+	cfg, err = LoadLintConfig(root)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.MaxCodeLine != 60 || !slices.Equal(cfg.BannedWords, []string{"óbvio"}) || !slices.Equal(cfg.Disabled, []string{"heading-style"}) {
+		t.Errorf("config = %+v", cfg)
+	}
+
+	for name, data := range map[string]string{
+		"regra desconhecida": `{"disabled": ["heading-color"]}`,
+		"JSON inválido":      `{"maxCodeLine": }`,
+	} {
+		if err := os.WriteFile(filepath.Join(root, lintFile), []byte(data), 0644); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := LoadLintConfig(root); err == nil {
+			t.Errorf("%s: lint.json aceito", name)
+		}
+	}
+}
@@ -1,122 +1,141 @@
 package main
 
 import (
+	"errors"
+	"flag"
 	"fmt"
-	"html/template"
+	"log/slog"
 	"net/http"
 	"os"
 	"path/filepath"
-
-	"github.com/google/uuid"
 )
 
-// TextSection representa uma seção individual do texto
-type TextSection struct {
-	ID       string
-	Title    string
-	Content  string
-	Order    int
-	FilePath string
-}
-
-// Document representa o documento completo
-type Document struct {
-	Title      string
-	Sections   []TextSection
-	OutputPath string
-}
-
 func main() {
-	doc := &Document{
-		Title:      "Novo Documento",
-		OutputPath: "output.md",
-	}
+	logger := slog.New(slog.NewTextHandler(os.Stderr, nil))
+	slog.SetDefault(logger)
 
-	if err := initializeWorkspace(); err != nil {
-		fmt.Printf("Erro ao inicializar workspace: %v\n", err)
+	cfg, args, err := parseConfig(os.Args[1:])
+	if errors.Is(err, flag.ErrHelp) {
+		return
+	}
+	if err != nil {
+		os.Exit(2) // a mensagem já foi impressa por parseConfig
+	}
+	if web, err = loadWebFiles(cfg); err != nil {
+		logger.Error("erro ao carregar a interface", "err", err)
 		os.Exit(1)
 	}
+	ws := NewWorkspace(cfg.Root)
 
-	// Configurar rotas para a interface web
-	http.HandleFunc("/", handleIndex(doc))
-	http.HandleFunc("/api/sections", handleSections(doc))
-
-	// Servir arquivos estáticos
-	fs := http.FileServer(http.Dir("static"))
-	http.Handle("/static/", http.StripPrefix("/static/", fs))
-
-	fmt.Println("Servidor rodando em http://localhost:8080")
-	if err := http.ListenAndServe(":8080", nil); err != nil {
-		fmt.Printf("Erro ao iniciar servidor: %v\n", err)
+	if err := initializeWorkspace(ws); err != nil {
+		logger.Error("erro ao inicializar workspace", "err", err)
 		os.Exit(1)
 	}
-}
 
-func handleIndex(doc *Document) http.HandlerFunc {
-	return func(w http.ResponseWriter, r *http.Request) {
-		tmpl, err := template.ParseFiles("templates/index.html")
-		if err != nil {
-			http.Error(w, err.Error(), http.StatusInternalServerError)
-			return
+	if len(args) > 0 && args[0] == "import" {
+		if err := runImport(ws, args[1:]); err != nil {
+			logger.Error("erro ao importar sumário", "err", err)
+			os.Exit(1)
 		}
-		tmpl.Execute(w, doc)
+		return
 	}
-}
 
-func handleSections(doc *Document) http.HandlerFunc {
-	return func(w http.ResponseWriter, r *http.Request) {
-		// TODO: Implementar API para gerenciar seções
+	if len(args) > 0 && args[0] == "user" {
+		if err := runUser(ws.Root, args[1:]); err != nil {
+			logger.Error("erro no comando user", "err", err)
+			os.Exit(1)
+		}
+		return
 	}
-}
 
-// initializeWorkspace cria a estrutura de diretórios necessária
-func initializeWorkspace() error {
-	dirs := []string{
-		"sections", // para armazenar seções individuais
-		"output",   // para o arquivo final combinado
+	users, err := LoadUsers(ws.Root)
+	if err != nil {
+		logger.Error("erro ao carregar usuários", "err", err)
+		os.Exit(1)
 	}
+	if users.Len() == 0 {
+		logger.Warn("nenhum usuário cadastrado; crie um com: go-writer user add <nome> editor")
+	}
+	auth := NewAuth(users, ws.Root)
 
-	for _, dir := range dirs {
-		if err := os.MkdirAll(dir, 0755); err != nil {
-			return fmt.Errorf("erro ao criar diretório %s: %v", dir, err)
-		}
+	if err := serve(cfg, ws, auth, logger); err != nil {
+		logger.Error("servidor encerrado com erro", "err", err)
+		os.Exit(1)
 	}
-	return nil
 }
 
-// SaveDocument salva o documento completo
-func (d *Document) SaveDocument() error {
-	// TODO: Implementar a lógica para combinar todas as seções
-	// e gerar o arquivo final com TOC
-	return nil
+// indexData alimenta o template da página principal
+type indexData struct {
+	Documents []DocumentInfo
+	Current   DocumentInfo
+	User      currentUser
 }
 
-// AddSection adiciona uma nova seção ao documento
-func (d *Document) AddSection(title, content string) error {
-	section := TextSection{
-		ID:      generateID(),
-		Title:   title,
-		Content: content,
-		Order:   len(d.Sections),
-	}
+// handleIndex exibe o editor do documento escolhido em ?doc=, ou do
+// primeiro documento do workspace
+func handleIndex(ws *Workspace) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/" {
+			http.NotFound(w, r)
+			return
+		}
 
-	// Criar arquivo para a seção
-	filename := filepath.Join("sections", fmt.Sprintf("%s.md", section.ID))
-	section.FilePath = filename
+		user, _ := requestUser(r)
+		docs := ws.List()
+		if len(docs) == 0 {
+			if !user.Role.Allows(RoleAuthor) {
+				http.Error(w, "nenhum documento no workspace", http.StatusNotFound)
+				return
+			}
+			doc, err := ws.Create(defaultTitle)
+			if err != nil {
+				http.Error(w, err.Error(), http.StatusInternalServerError)
+				return
+			}
+			docs = append(docs, doc)
+		}
 
-	// TODO: Implementar salvamento do conteúdo no arquivo
+		current := docs[0]
+		if id := r.URL.Query().Get("doc"); id != "" {
+			doc, err := ws.Get(id)
+			if err != nil {
+				http.Error(w, err.Error(), http.StatusNotFound)
+				return
+			}
+			current = doc
+		}
 
-	d.Sections = append(d.Sections, section)
-	return nil
+		tmpl, err := web.page("index.html")
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		data := indexData{Current: current.Info(), User: user}
+		for _, doc := range docs {
+			data.Documents = append(data.Documents, doc.Info())
+		}
+		tmpl.Execute(w, data)
+	}
 }
 
-// ReorderSections reordena as seções do documento
-func (d *Document) ReorderSections(newOrder []string) error {
-	// TODO: Implementar reordenação das seções
-	return nil
-}
+// initializeWorkspace cria a estrutura de diretórios necessária, migra
+// workspaces do formato de documento único, lê lint.json e recarrega os
+// documentos já salvos em disco. O documento padrão só é criado pela página
+// principal, quando não houver nenhum.
+func initializeWorkspace(ws *Workspace) error {
+	dir := filepath.Join(ws.Root, documentsDir)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return fmt.Errorf("erro ao criar diretório %s: %v", dir, err)
+	}
 
-// generateID gera um ID único usando UUID
-func generateID() string {
-	return uuid.New().String()
+	if err := ws.migrateLegacy(); err != nil {
+		return err
+	}
+
+	lint, err := LoadLintConfig(ws.Root)
+	if err != nil {
+		return err
+	}
+	ws.Lint = lint
+	return ws.Load()
 }
@@ -0,0 +1,130 @@
+package main
+
+import (
+	"bytes"
+	"net/http"
+	"regexp"
+	"sync"
+
+	chromahtml "github.com/alecthomas/chroma/v2/formatters/html"
+	"github.com/alecthomas/chroma/v2/styles"
+	"github.com/microcosm-cc/bluemonday"
+	"github.com/yuin/goldmark"
+	emoji "github.com/yuin/goldmark-emoji"
+	highlighting "github.com/yuin/goldmark-highlighting/v2"
+	"github.com/yuin/goldmark/ast"
+	"github.com/yuin/goldmark/extension"
+	"github.com/yuin/goldmark/parser"
+	"github.com/yuin/goldmark/renderer/html"
+)
+
+// previewStyle é o tema do chroma usado nos blocos de código
+const previewStyle = "github"
+
+// maxPreviewBytes limita o tamanho do Markdown aceito por /api/preview
+const maxPreviewBytes = 2 << 20
+
+// markdown renderiza o Markdown dos capítulos: tabelas e demais extensões
+// do GitHub, emoji (:rocket:) e blocos ```go com realce de sintaxe. O HTML
+// cru é mantido aqui e filtrado depois por sanitizer.
+var markdown = goldmark.New(
+	goldmark.WithExtensions(
+		extension.GFM,
+		emoji.Emoji,
+		highlighting.NewHighlighting(
+			highlighting.WithStyle(previewStyle),
+			highlighting.WithFormatOptions(chromahtml.WithClasses(true)),
+		),
+	),
+	goldmark.WithParserOptions(parser.WithAutoHeadingID()),
+	goldmark.WithRendererOptions(html.WithUnsafe()),
+)
+
+// sanitizer remove scripts, handlers e afins do HTML gerado, mantendo as
+// classes do chroma e os IDs dos headings
+var sanitizer = func() *bluemonday.Policy {
+	p := bluemonday.UGCPolicy()
+	p.AllowAttrs("class").Matching(regexp.MustCompile(`^[\w -]+$`)).
+		OnElements("span", "pre", "code", "div")
+	p.AllowAttrs("id").Matching(bluemonday.SpaceSeparatedTokens).
+		OnElements("h1", "h2", "h3", "h4", "h5", "h6")
+	return p
+}()
+
+// headingIDs faz o goldmark gerar IDs de heading no formato do GitHub,
+// os mesmos usados no sumário de SaveDocument
+type headingIDs struct {
+	slugs *slugger
+}
+
+func (h headingIDs) Generate(value []byte, kind ast.NodeKind) []byte {
+	return []byte(h.slugs.slug(string(value)))
+}
+
+func (h headingIDs) Put(value []byte) {
+	h.slugs.seen[string(value)] = 0
+}
+
+// RenderMarkdown converte Markdown em HTML sanitizado
+func RenderMarkdown(source string) ([]byte, error) {
+	ctx := parser.NewContext(parser.WithIDs(headingIDs{slugs: newSlugger()}))
+
+	var buf bytes.Buffer
+	if err := markdown.Convert([]byte(source), &buf, parser.WithContext(ctx)); err != nil {
+		return nil, err
+	}
+	return sanitizer.SanitizeBytes(buf.Bytes()), nil
+}
+
+// highlightCSS gera uma única vez o CSS das classes do chroma
+var highlightCSS = sync.OnceValues(func() ([]byte, error) {
+	var buf bytes.Buffer
+	formatter := chromahtml.New(chromahtml.WithClasses(true))
+	if err := formatter.WriteCSS(&buf, styles.Get(previewStyle)); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+})
+
+// previewRequest é o corpo de POST /api/preview
+type previewRequest struct {
+	Content string `json:"content"`
+}
+
+// previewResponse devolve o HTML pronto para ser inserido na página
+type previewResponse struct {
+	HTML string `json:"html"`
+}
+
+// handlePreview renderiza o Markdown enviado pelo editor
+func handlePreview(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.Header().Set("Allow", "POST")
+		http.Error(w, "método não permitido", http.StatusMethodNotAllowed)
+		return
+	}
+
+	r.Body = http.MaxBytesReader(w, r.Body, maxPreviewBytes)
+	var req previewRequest
+	if !decodeJSON(w, r, &req) {
+		return
+	}
+
+	out, err := RenderMarkdown(req.Content)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	writeJSON(w, http.StatusOK, previewResponse{HTML: string(out)})
+}
+
+// handleHighlightCSS serve o CSS do realce de sintaxe usado no preview
+func handleHighlightCSS(w http.ResponseWriter, r *http.Request) {
+	css, err := highlightCSS()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "text/css; charset=utf-8")
+	w.Write(css)
+}
@@ -0,0 +1,106 @@
+package main
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func TestRenderMarkdown(t *testing.T) {
+	tests := []struct {
+		name    string
+		source  string
+		want    []string // trechos que o HTML deve conter
+		notWant []string // trechos que o sanitizer deve ter removido
+	}{
+		{
+			name:   "tabela GFM",
+			source: "| Tipo | Zero |\n|------|------|\n| int | 0 |\n| string | \"\" |\n",
+			want: []string{
+				"<table>", "<th>Tipo</th>", "<th>Zero</th>",
+				"<td>int</td>", "<td>0</td>", "<td>string</td>",
+			},
+		},
+		{
+			name:   "realce de Go",
+			source: "```go\nfunc main() {\n\tfmt.Println(\"oi\")\n}\n```\n",
+			want: []string{
+				`<pre class="chroma">`,
+				`<span class="kd">func</span>`,
+				`<span class="nf">main</span>`,
+				`<span class="s">&#34;oi&#34;</span>`,
+			},
+		},
+		{
+			name:    "script",
+			source:  "Antes <script>alert(1)</script> depois\n\n<script>\nalert(2)\n</script>\n",
+			want:    []string{"Antes", "depois"},
+			notWant: []string{"<script", "alert"},
+		},
+		{
+			name:    "links javascript:",
+			source:  "[um](javascript:alert(1)) e <a href=\"javascript:alert(2)\">dois</a>\n",
+			want:    []string{"um", "dois"},
+			notWant: []string{"javascript:", "href"},
+		},
+		{
+			name:    "atributos de evento",
+			source:  "<img src=\"x.png\" onerror=\"alert(1)\"> <b onclick=\"alert(2)\">b</b>\n",
+			want:    []string{`<img src="x.png">`, "<b>b</b>"},
+			notWant: []string{"onerror", "onclick"},
+		},
+		{
+			name:   "links comuns continuam",
+			source: "[Go](https://go.dev) e [seção](#olá-mundo)\n",
+			want:   []string{`<a href="https://go.dev" rel="nofollow">Go</a>`, `<a href="#ol%C3%A1-mundo"`},
+		},
+		{
+			name:   "IDs dos headings como no sumário",
+			source: "# Olá, mundo!\n\n## Olá, mundo!\n",
+			want:   []string{`<h1 id="olá-mundo">`, `<h2 id="olá-mundo-1">`},
+		},
+		{
+			name:   "emoji",
+			source: "Lançou :rocket:\n",
+			want:   []string{"Lançou 🚀"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			out, err := RenderMarkdown(tt.source)
+			if err != nil {
+				t.Fatal(err)
+			}
+			html := string(out)
+			for _, s := range tt.want {
+				if !strings.Contains(html, s) {
+					t.Errorf("HTML sem %q:\n%s", s, html)
+				}
+			}
+			for _, s := range tt.notWant {
+				if strings.Contains(html, s) {
+					t.Errorf("HTML com %q:\n%s", s, html)
+				}
+			}
+		})
+	}
+}
+
+func TestPreviewEndpoint(t *testing.T) {
+	ts := newTestServer(t)
+	author := ts.login(t, "author")
+
+	var got previewResponse
+	resp := author.expect(http.StatusOK, http.MethodPost, "/api/preview",
+		previewRequest{Content: "**oi** <script>alert(1)</script>"})
+	decode(t, resp, &got)
+	if got.HTML != "<p><strong>oi</strong> </p>\n" {
+		t.Errorf("html = %q", got.HTML)
+	}
+
+	resp = author.expect(http.StatusOK, http.MethodGet, "/api/preview/highlight.css", nil)
+	if ct := resp.Header.Get("Content-Type"); !strings.HasPrefix(ct, "text/css") {
+		t.Errorf("Content-Type = %s", ct)
+	}
+}
@@ -0,0 +1,196 @@
+package main
+
+import (
+	"bufio"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// revisionsDir guarda um log append-only por seção: revisions/<id>.jsonl
+const revisionsDir = "revisions"
+
+// unknownAuthor é registrado quando a alteração não informa o autor
+const unknownAuthor = "desconhecido"
+
+// ErrRevisionNotFound é retornado quando o número não existe no log
+var ErrRevisionNotFound = errors.New("revisão não encontrada")
+
+// Revision é uma versão salva de uma seção. Number começa em 1 e cresce
+// a cada gravação; Hash é o SHA-256 do conteúdo.
+type Revision struct {
+	Number    int
+	Timestamp time.Time
+	Author    string
+	Hash      string
+	Title     string
+	Content   string `json:",omitempty"`
+}
+
+// contentHash retorna o SHA-256 do conteúdo em hexadecimal
+func contentHash(content string) string {
+	sum := sha256.Sum256([]byte(content))
+	return hex.EncodeToString(sum[:])
+}
+
+// revisionsFile retorna o caminho do log de revisões da seção
+func (d *Document) revisionsFile(sectionID string) string {
+	return filepath.Join(d.Dir, revisionsDir, sectionID+".jsonl")
+}
+
+// Revisions lê o log completo de revisões da seção, da mais antiga para
+// a mais recente
+func (d *Document) Revisions(sectionID string) ([]Revision, error) {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+	return d.revisions(sectionID)
+}
+
+func (d *Document) revisions(sectionID string) ([]Revision, error) {
+	if !validSectionID(sectionID) {
+		return nil, fmt.Errorf("%w: %q", ErrSectionNotFound, sectionID)
+	}
+	f, err := os.Open(d.revisionsFile(sectionID))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("erro ao ler revisões de %s: %v", sectionID, err)
+	}
+	defer f.Close()
+
+	var revisions []Revision
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 64*1024), 64<<20)
+	for scanner.Scan() {
+		var rev Revision
+		if err := json.Unmarshal(scanner.Bytes(), &rev); err != nil {
+			return nil, fmt.Errorf("revisão corrompida em %s: %v", sectionID, err)
+		}
+		revisions = append(revisions, rev)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("erro ao ler revisões de %s: %v", sectionID, err)
+	}
+	return revisions, nil
+}
+
+// Revision retorna uma revisão específica da seção
+func (d *Document) Revision(sectionID string, number int) (*Revision, error) {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+	return d.revision(sectionID, number)
+}
+
+func (d *Document) revision(sectionID string, number int) (*Revision, error) {
+	revisions, err := d.revisions(sectionID)
+	if err != nil {
+		return nil, err
+	}
+	for i := range revisions {
+		if revisions[i].Number == number {
+			return &revisions[i], nil
+		}
+	}
+	return nil, fmt.Errorf("%w: %s#%d", ErrRevisionNotFound, sectionID, number)
+}
+
+// recordRevision acrescenta o estado atual da seção ao log. Gravações que
+// não mudam título nem conteúdo não geram revisão nova.
+func (d *Document) recordRevision(s *TextSection, author string) error {
+	revisions, err := d.revisions(s.ID)
+	if err != nil {
+		return err
+	}
+
+	hash := contentHash(s.Content)
+	number := 1
+	if n := len(revisions); n > 0 {
+		last := revisions[n-1]
+		if last.Hash == hash && last.Title == s.Title {
+			return nil
+		}
+		number = last.Number + 1
+	}
+
+	if author == "" {
+		author = unknownAuthor
+	}
+	return d.appendRevision(s.ID, Revision{
+		Number:    number,
+		Timestamp: time.Now().UTC(),
+		Author:    author,
+		Hash:      hash,
+		Title:     s.Title,
+		Content:   s.Content,
+	})
+}
+
+// ensureBaseline registra a versão atual de seções criadas antes do
+// histórico existir, para que a primeira edição não apague o texto antigo
+func (d *Document) ensureBaseline(s *TextSection) error {
+	revisions, err := d.revisions(s.ID)
+	if err != nil || len(revisions) > 0 {
+		return err
+	}
+	return d.recordRevision(s, unknownAuthor)
+}
+
+// appendRevision grava uma linha no log; o arquivo só cresce
+func (d *Document) appendRevision(sectionID string, rev Revision) error {
+	path := d.revisionsFile(sectionID)
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+
+	data, err := json.Marshal(rev)
+	if err != nil {
+		return err
+	}
+
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return fmt.Errorf("erro ao gravar revisão de %s: %v", sectionID, err)
+	}
+	if _, err := f.Write(append(data, '\n')); err != nil {
+		f.Close()
+		return fmt.Errorf("erro ao gravar revisão de %s: %v", sectionID, err)
+	}
+	return f.Close()
+}
+
+// DiffRevisions retorna o diff unificado do conteúdo entre duas revisões
+func (d *Document) DiffRevisions(sectionID string, from, to int) (string, error) {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+
+	a, err := d.revision(sectionID, from)
+	if err != nil {
+		return "", err
+	}
+	b, err := d.revision(sectionID, to)
+	if err != nil {
+		return "", err
+	}
+	return UnifiedDiff(
+		fmt.Sprintf("%s#%d", sectionID, from),
+		fmt.Sprintf("%s#%d", sectionID, to),
+		a.Content, b.Content,
+	), nil
+}
+
+// RestoreRevision volta a seção para o título e conteúdo de uma revisão
+// anterior. A restauração vira uma revisão nova; o log nunca é reescrito.
+// version segue a mesma regra de UpdateSection.
+func (d *Document) RestoreRevision(sectionID string, number int, author string, version int) (*TextSection, error) {
+	rev, err := d.Revision(sectionID, number)
+	if err != nil {
+		return nil, err
+	}
+	return d.UpdateSection(sectionID, &rev.Title, &rev.Content, author, version)
+}
@@ -0,0 +1,48 @@
+package main
+
+import (
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func TestRevisionRoutesRejectPathTraversal(t *testing.T) {
+	ts := newTestServer(t)
+	reader := ts.login(t, "reader")
+
+	// um .jsonl qualquer na raiz do workspace, fora do documento
+	writeFiles(t, ts.ws.Root, map[string]string{
+		"segredo.jsonl": `{"Number": 1, "Title": "x", "Content": "conteúdo secreto"}` + "\n",
+	})
+
+	// revisions/<id>.jsonl com id = ../../../segredo cai na raiz
+	id := "..%2F..%2F..%2Fsegredo"
+	for _, path := range []string{
+		ts.docPath("sections", id, "revisions", "1"),
+		ts.docPath("sections", id, "diff") + "?from=1",
+		ts.docPath("sections", id, "diff") + "?from=1&to=1",
+	} {
+		resp := reader.do(http.MethodGet, path, nil)
+		body, _ := io.ReadAll(resp.Body)
+		if resp.StatusCode != http.StatusNotFound {
+			t.Errorf("GET %s: status %d, esperado 404", path, resp.StatusCode)
+		}
+		if strings.Contains(string(body), "conteúdo secreto") {
+			t.Errorf("GET %s leu arquivo fora do documento: %s", path, body)
+		}
+	}
+}
+
+func TestValidSectionID(t *testing.T) {
+	for _, id := range []string{generateID(), "intro", "cap-1.2"} {
+		if !validSectionID(id) {
+			t.Errorf("validSectionID(%q) = false", id)
+		}
+	}
+	for _, id := range []string{"", ".", "..", "../audit", `..\audit`, "a/b", "a\x00"} {
+		if validSectionID(id) {
+			t.Errorf("validSectionID(%q) = true", id)
+		}
+	}
+}
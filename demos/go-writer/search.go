@@ -0,0 +1,363 @@
+package main
+
+import (
+	"html"
+	"math"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"golang.org/x/text/runes"
+	"golang.org/x/text/transform"
+	"golang.org/x/text/unicode/norm"
+)
+
+// titleWeight faz uma ocorrência no título valer mais que no conteúdo
+const titleWeight = 3
+
+// snippetRadius é quantos caracteres mostrar antes e depois do trecho
+const snippetRadius = 80
+
+// defaultSearchLimit é o número máximo de resultados de /api/search
+const defaultSearchLimit = 20
+
+// posting conta as ocorrências de um termo em uma seção
+type posting struct {
+	title   int
+	content int
+}
+
+// searchIndex é um índice invertido em memória sobre títulos e conteúdos
+// das seções de um documento. Os termos são normalizados por foldTerm, de
+// modo que "funcao" encontra "função". Deve ser usado com d.mu travado.
+type searchIndex struct {
+	postings map[string]map[string]posting // termo → seção → ocorrências
+	terms    map[string][]string           // seção → termos indexados
+	lengths  map[string]int                // seção → total de termos
+}
+
+func newSearchIndex() *searchIndex {
+	return &searchIndex{
+		postings: make(map[string]map[string]posting),
+		terms:    make(map[string][]string),
+		lengths:  make(map[string]int),
+	}
+}
+
+// add (re)indexa a seção, substituindo o que havia antes
+func (idx *searchIndex) add(s *TextSection) {
+	idx.remove(s.ID)
+
+	counts := make(map[string]posting)
+	total := 0
+	for _, tok := range tokenize(s.Title) {
+		p := counts[tok.term]
+		p.title++
+		counts[tok.term] = p
+		total++
+	}
+	for _, tok := range tokenize(s.Content) {
+		p := counts[tok.term]
+		p.content++
+		counts[tok.term] = p
+		total++
+	}
+
+	terms := make([]string, 0, len(counts))
+	for term, p := range counts {
+		if idx.postings[term] == nil {
+			idx.postings[term] = make(map[string]posting)
+		}
+		idx.postings[term][s.ID] = p
+		terms = append(terms, term)
+	}
+	idx.terms[s.ID] = terms
+	idx.lengths[s.ID] = total
+}
+
+// remove tira a seção do índice
+func (idx *searchIndex) remove(id string) {
+	for _, term := range idx.terms[id] {
+		delete(idx.postings[term], id)
+		if len(idx.postings[term]) == 0 {
+			delete(idx.postings, term)
+		}
+	}
+	delete(idx.terms, id)
+	delete(idx.lengths, id)
+}
+
+// search retorna as seções que contêm todos os termos, com pontuação
+// TF-IDF normalizada pelo tamanho da seção
+func (idx *searchIndex) search(terms []string) map[string]float64 {
+	if len(terms) == 0 {
+		return nil
+	}
+
+	n := float64(len(idx.lengths))
+	scores := make(map[string]float64)
+	for i, term := range terms {
+		postings := idx.postings[term]
+		if len(postings) == 0 {
+			return nil
+		}
+		idf := math.Log(1 + n/float64(len(postings)))
+
+		next := make(map[string]float64, len(postings))
+		for id, p := range postings {
+			prev, ok := scores[id]
+			if i > 0 && !ok {
+				continue
+			}
+			tf := float64(titleWeight*p.title + p.content)
+			length := 1 + math.Log(1+float64(idx.lengths[id]))
+			next[id] = prev + idf*tf/length
+		}
+		scores = next
+	}
+	return scores
+}
+
+// token é um termo normalizado e sua posição (em bytes) no texto original
+type token struct {
+	term       string
+	start, end int
+}
+
+// tokenize quebra o texto em palavras (letras e dígitos) normalizadas
+func tokenize(text string) []token {
+	var tokens []token
+	start := -1
+	for i, r := range text {
+		isWord := unicode.IsLetter(r) || unicode.IsDigit(r) || unicode.Is(unicode.Mn, r)
+		switch {
+		case isWord && start < 0:
+			start = i
+		case !isWord && start >= 0:
+			tokens = append(tokens, token{foldTerm(text[start:i]), start, i})
+			start = -1
+		}
+	}
+	if start >= 0 {
+		tokens = append(tokens, token{foldTerm(text[start:]), start, len(text)})
+	}
+	return tokens
+}
+
+// foldTerm coloca em minúsculas e remove acentos: "Função" → "funcao"
+func foldTerm(s string) string {
+	if isASCII(s) {
+		return strings.ToLower(s)
+	}
+	t := transform.Chain(norm.NFD, runes.Remove(runes.In(unicode.Mn)), norm.NFC)
+	folded, _, err := transform.String(t, strings.ToLower(s))
+	if err != nil {
+		return strings.ToLower(s)
+	}
+	return folded
+}
+
+// queryTerms normaliza a consulta, descartando termos repetidos
+func queryTerms(q string) []string {
+	var terms []string
+	seen := make(map[string]bool)
+	for _, tok := range tokenize(q) {
+		if !seen[tok.term] {
+			seen[tok.term] = true
+			terms = append(terms, tok.term)
+		}
+	}
+	return terms
+}
+
+// searchIndex retorna o índice do documento, criando-o se preciso.
+// Deve ser chamado com d.mu travado para escrita.
+func (d *Document) searchIndex() *searchIndex {
+	if d.index == nil {
+		d.index = newSearchIndex()
+	}
+	return d.index
+}
+
+// SearchHit é um resultado da busca
+type SearchHit struct {
+	DocumentID    string
+	DocumentTitle string
+	SectionID     string
+	Title         string
+	TitleHTML     string
+	Snippet       string
+	Score         float64
+}
+
+// Search procura a consulta nas seções do documento. TitleHTML e Snippet
+// são HTML escapado, com os termos encontrados dentro de <mark>.
+func (d *Document) Search(q string) []SearchHit {
+	terms := queryTerms(q)
+
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+
+	if d.index == nil {
+		return nil
+	}
+
+	var hits []SearchHit
+	for id, score := range d.index.search(terms) {
+		i := d.indexOf(id)
+		if i < 0 {
+			continue
+		}
+		s := d.Sections[i]
+		hits = append(hits, SearchHit{
+			DocumentID:    d.ID,
+			DocumentTitle: d.Title,
+			SectionID:     s.ID,
+			Title:         s.Title,
+			TitleHTML:     highlight(s.Title, terms, 0, len(s.Title)),
+			Snippet:       snippet(s.Content, terms),
+			Score:         score,
+		})
+	}
+	sortHits(hits)
+	return hits
+}
+
+// sortHits ordena por pontuação e, no empate, por título
+func sortHits(hits []SearchHit) {
+	sort.Slice(hits, func(i, j int) bool {
+		if hits[i].Score != hits[j].Score {
+			return hits[i].Score > hits[j].Score
+		}
+		return hits[i].Title < hits[j].Title
+	})
+}
+
+// snippet recorta o conteúdo em volta da primeira ocorrência de um termo
+func snippet(content string, terms []string) string {
+	first := -1
+	for _, tok := range tokenize(content) {
+		if containsTerm(terms, tok.term) {
+			first = tok.start
+			break
+		}
+	}
+	if first < 0 {
+		// termo só no título: mostra o começo do texto
+		first = 0
+	}
+
+	start := runeOffset(content, first, -snippetRadius)
+	end := runeOffset(content, first, snippetRadius)
+
+	out := highlight(content, terms, start, end)
+	out = strings.Join(strings.Fields(out), " ")
+	if start > 0 {
+		out = "…" + out
+	}
+	if end < len(content) {
+		out += "…"
+	}
+	return out
+}
+
+// highlight escapa text[start:end] como HTML e marca os termos com <mark>
+func highlight(text string, terms []string, start, end int) string {
+	var b strings.Builder
+	pos := start
+	for _, tok := range tokenize(text[start:end]) {
+		if !containsTerm(terms, tok.term) {
+			continue
+		}
+		b.WriteString(html.EscapeString(text[pos : start+tok.start]))
+		b.WriteString("<mark>")
+		b.WriteString(html.EscapeString(text[start+tok.start : start+tok.end]))
+		b.WriteString("</mark>")
+		pos = start + tok.end
+	}
+	b.WriteString(html.EscapeString(text[pos:end]))
+	return b.String()
+}
+
+// runeOffset anda n runas a partir de pos (para trás se n < 0),
+// sem cortar caracteres multibyte ao meio
+func runeOffset(s string, pos, n int) int {
+	for ; n < 0 && pos > 0; n++ {
+		_, size := utf8.DecodeLastRuneInString(s[:pos])
+		pos -= size
+	}
+	for ; n > 0 && pos < len(s); n-- {
+		_, size := utf8.DecodeRuneInString(s[pos:])
+		pos += size
+	}
+	return pos
+}
+
+func isASCII(s string) bool {
+	for i := 0; i < len(s); i++ {
+		if s[i] >= utf8.RuneSelf {
+			return false
+		}
+	}
+	return true
+}
+
+func containsTerm(terms []string, term string) bool {
+	for _, t := range terms {
+		if t == term {
+			return true
+		}
+	}
+	return false
+}
+
+// handleSearch atende GET /api/search?q=, buscando em todos os documentos
+// do workspace ou apenas no indicado por &doc=
+func handleSearch(ws *Workspace) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			w.Header().Set("Allow", "GET")
+			http.Error(w, "método não permitido", http.StatusMethodNotAllowed)
+			return
+		}
+
+		q := strings.TrimSpace(r.URL.Query().Get("q"))
+		if q == "" {
+			http.Error(w, "parâmetro q é obrigatório", http.StatusBadRequest)
+			return
+		}
+
+		limit := defaultSearchLimit
+		if l := r.URL.Query().Get("limit"); l != "" {
+			n, err := strconv.Atoi(l)
+			if err != nil || n < 1 {
+				http.Error(w, "limit inválido", http.StatusBadRequest)
+				return
+			}
+			limit = n
+		}
+
+		docs := ws.List()
+		if id := r.URL.Query().Get("doc"); id != "" {
+			doc, err := ws.Get(id)
+			if err != nil {
+				writeSectionError(w, err)
+				return
+			}
+			docs = []*Document{doc}
+		}
+
+		hits := []SearchHit{}
+		for _, doc := range docs {
+			hits = append(hits, doc.Search(q)...)
+		}
+		sortHits(hits)
+		if len(hits) > limit {
+			hits = hits[:limit]
+		}
+		writeJSON(w, http.StatusOK, hits)
+	}
+}
@@ -0,0 +1,186 @@
+package main
+
+import (
+	"html"
+	"net/http"
+	"slices"
+	"strings"
+	"testing"
+)
+
+// hitTitles lista os títulos dos resultados, na ordem da busca
+func hitTitles(hits []SearchHit) []string {
+	titles := make([]string, len(hits))
+	for i, h := range hits {
+		titles[i] = h.Title
+	}
+	return titles
+}
+
+// addTestSection cria uma seção na raiz do documento
+func addTestSection(t *testing.T, doc *Document, title, content string) *TextSection {
+	t.Helper()
+	s, err := doc.AddSection(title, content, "", "teste")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return s
+}
+
+func TestSearchFoldsAccents(t *testing.T) {
+	doc := newTestDocument(t)
+	addTestSection(t, doc, "Ação e reação", "Funções puras não têm efeitos colaterais.")
+	addTestSection(t, doc, "Outra", "Nada a ver.")
+
+	for _, q := range []string{"acao", "AÇÃO", "Acão", "funcoes", "nao tem", "EFEITOS colaterais"} {
+		if got := hitTitles(doc.Search(q)); !slices.Equal(got, []string{"Ação e reação"}) {
+			t.Errorf("Search(%q) = %q", q, got)
+		}
+	}
+	// todos os termos precisam aparecer na seção
+	if got := doc.Search("acao inexistente"); len(got) != 0 {
+		t.Errorf("busca com termo ausente encontrou %q", hitTitles(got))
+	}
+	if got := doc.Search("  ...  "); len(got) != 0 {
+		t.Errorf("busca sem termos encontrou %q", hitTitles(got))
+	}
+}
+
+func TestSearchRanking(t *testing.T) {
+	doc := newTestDocument(t)
+	addTestSection(t, doc, "Apêndice", "Canais aparecem uma vez aqui, entre muitas outras palavras do texto.")
+	addTestSection(t, doc, "Goroutines", "Canais, canais e mais canais.")
+	addTestSection(t, doc, "Canais", "Comunicação entre goroutines.")
+	addTestSection(t, doc, "Mapas", "Sem relação.")
+
+	// título vale mais que conteúdo; no conteúdo, mais ocorrências em um
+	// texto mais curto pontuam mais
+	want := []string{"Canais", "Goroutines", "Apêndice"}
+	hits := doc.Search("canais")
+	if got := hitTitles(hits); !slices.Equal(got, want) {
+		t.Fatalf("ordem = %q, esperado %q", got, want)
+	}
+	for i := 1; i < len(hits); i++ {
+		if hits[i].Score >= hits[i-1].Score {
+			t.Errorf("pontuação fora de ordem: %v", hits)
+		}
+	}
+}
+
+func TestSearchSnippet(t *testing.T) {
+	doc := newTestDocument(t)
+	content := strings.Repeat("antes ", 30) + "a <b>concorrência</b> em Go " + strings.Repeat("depois ", 30)
+	addTestSection(t, doc, "Concorrência & paralelismo", content)
+
+	hits := doc.Search("concorrencia")
+	if len(hits) != 1 {
+		t.Fatalf("%d resultados", len(hits))
+	}
+	hit := hits[0]
+	if hit.TitleHTML != "<mark>Concorrência</mark> &amp; paralelismo" {
+		t.Errorf("TitleHTML = %q", hit.TitleHTML)
+	}
+	if !strings.Contains(hit.Snippet, "a &lt;b&gt;<mark>concorrência</mark>&lt;/b&gt; em Go") {
+		t.Errorf("trecho sem o termo marcado e escapado: %q", hit.Snippet)
+	}
+	if !strings.HasPrefix(hit.Snippet, "…") || !strings.HasSuffix(hit.Snippet, "…") {
+		t.Errorf("trecho sem reticências nas pontas cortadas: %q", hit.Snippet)
+	}
+	// o texto visível tem até snippetRadius caracteres de cada lado do
+	// termo, mais as reticências
+	visible := html.UnescapeString(strings.NewReplacer("<mark>", "", "</mark>", "").Replace(hit.Snippet))
+	if n := len([]rune(visible)); n > 2*snippetRadius+2 {
+		t.Errorf("trecho com %d caracteres: %q", n, visible)
+	}
+
+	// termo só no título: o trecho mostra o começo do texto
+	addTestSection(t, doc, "Canais", "Texto curto.")
+	if hits := doc.Search("canais"); len(hits) != 1 || hits[0].Snippet != "Texto curto." {
+		t.Errorf("trecho = %+v", hits)
+	}
+}
+
+func TestSearchIndexFollowsChanges(t *testing.T) {
+	doc := newTestDocument(t)
+	s := addTestSection(t, doc, "Intro", "Texto sobre ponteiros.")
+
+	search := func(q string) []string {
+		t.Helper()
+		return hitTitles(doc.Search(q))
+	}
+	if got := search("ponteiros"); len(got) != 1 {
+		t.Fatalf("seção nova fora do índice: %q", got)
+	}
+
+	// edição: o termo antigo sai, o novo entra
+	title, content := "Introdução", "Texto sobre interfaces."
+	if _, err := doc.UpdateSection(s.ID, &title, &content, "teste", 0); err != nil {
+		t.Fatal(err)
+	}
+	if got := search("ponteiros"); len(got) != 0 {
+		t.Errorf("termo removido ainda encontrado: %q", got)
+	}
+	if got := search("introducao interfaces"); !slices.Equal(got, []string{"Introdução"}) {
+		t.Errorf("edição fora do índice: %q", got)
+	}
+
+	// revisão restaurada volta ao conteúdo antigo
+	if _, err := doc.RestoreRevision(s.ID, 1, "teste", 0); err != nil {
+		t.Fatal(err)
+	}
+	if got := search("ponteiros"); !slices.Equal(got, []string{"Intro"}) {
+		t.Errorf("revisão restaurada fora do índice: %q", got)
+	}
+
+	// exclusão e restauração da lixeira
+	if err := doc.DeleteSection(s.ID, 0, "teste"); err != nil {
+		t.Fatal(err)
+	}
+	if got := search("ponteiros"); len(got) != 0 {
+		t.Errorf("seção excluída ainda encontrada: %q", got)
+	}
+	if _, err := doc.RestoreSection(s.ID); err != nil {
+		t.Fatal(err)
+	}
+	if got := search("ponteiros"); !slices.Equal(got, []string{"Intro"}) {
+		t.Errorf("seção restaurada fora do índice: %q", got)
+	}
+
+	// o índice é refeito ao carregar o documento do disco
+	if got := hitTitles(reloadDocument(t, doc).Search("ponteiros")); !slices.Equal(got, []string{"Intro"}) {
+		t.Errorf("índice após recarregar: %q", got)
+	}
+}
+
+func TestSearchEndpoint(t *testing.T) {
+	ts := newTestServer(t)
+	other, err := ts.ws.Create("Outro livro")
+	if err != nil {
+		t.Fatal(err)
+	}
+	addTestSection(t, ts.doc, "Slices", "Fatias sobre arrays.")
+	addTestSection(t, other, "Arrays", "Tamanho fixo.")
+	reader := ts.login(t, "reader")
+
+	var hits []SearchHit
+	decode(t, reader.expect(http.StatusOK, http.MethodGet, "/api/search?q=arrays", nil), &hits)
+	if got := hitTitles(hits); !slices.Equal(got, []string{"Arrays", "Slices"}) {
+		t.Errorf("busca no workspace = %q", got)
+	}
+	if hits[0].DocumentID != other.ID || hits[0].DocumentTitle != "Outro livro" {
+		t.Errorf("resultado sem o documento: %+v", hits[0])
+	}
+
+	decode(t, reader.expect(http.StatusOK, http.MethodGet, "/api/search?q=arrays&doc="+ts.doc.ID, nil), &hits)
+	if got := hitTitles(hits); !slices.Equal(got, []string{"Slices"}) {
+		t.Errorf("busca no documento = %q", got)
+	}
+	decode(t, reader.expect(http.StatusOK, http.MethodGet, "/api/search?q=arrays&limit=1", nil), &hits)
+	if len(hits) != 1 {
+		t.Errorf("limit=1 devolveu %d resultados", len(hits))
+	}
+
+	reader.expect(http.StatusBadRequest, http.MethodGet, "/api/search?q=", nil)
+	reader.expect(http.StatusBadRequest, http.MethodGet, "/api/search?q=a&limit=0", nil)
+	reader.expect(http.StatusNotFound, http.MethodGet, "/api/search?q=a&doc=nenhum", nil)
+}
@@ -0,0 +1,261 @@
+package main
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"flag"
+	"fmt"
+	"log/slog"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"regexp"
+	"syscall"
+	"time"
+)
+
+// Limites do servidor HTTP. Não há WriteTimeout global: o stream de
+// eventos fica aberto indefinidamente; as demais respostas são curtas.
+const (
+	readHeaderTimeout = 10 * time.Second
+	readTimeout       = time.Minute // uploads de até maxAssetBytes
+	idleTimeout       = 2 * time.Minute
+	shutdownTimeout   = 15 * time.Second
+)
+
+// requestIDHeader identifica a requisição nos logs e na resposta
+const requestIDHeader = "X-Request-ID"
+
+// validRequestID limita os IDs aceitos de proxies à frente do servidor
+var validRequestID = regexp.MustCompile(`^[A-Za-z0-9._-]{1,64}$`)
+
+// config são as opções do servidor. Cada flag tem uma variável de
+// ambiente equivalente, usada como valor padrão.
+type config struct {
+	Addr      string
+	Root      string
+	Templates string
+	Static    string
+	Dev       bool
+	Retention time.Duration // tempo na lixeira; 0 nunca esvazia
+}
+
+// envOr retorna a variável de ambiente key, ou def se estiver vazia
+func envOr(key, def string) string {
+	if v := os.Getenv(key); v != "" {
+		return v
+	}
+	return def
+}
+
+// parseConfig lê as flags da linha de comando e devolve os argumentos
+// restantes (subcomandos como import e user)
+func parseConfig(args []string) (config, []string, error) {
+	var cfg config
+	fs := flag.NewFlagSet("go-writer", flag.ContinueOnError)
+	fs.StringVar(&cfg.Addr, "addr", envOr("GOWRITER_ADDR", ":8080"), "endereço de escuta do servidor (GOWRITER_ADDR)")
+	fs.StringVar(&cfg.Root, "workspace", envOr("GOWRITER_WORKSPACE", "."), "diretório raiz do workspace (GOWRITER_WORKSPACE)")
+	fs.StringVar(&cfg.Templates, "templates", os.Getenv("GOWRITER_TEMPLATES"), "diretório dos templates HTML; vazio usa os embutidos (GOWRITER_TEMPLATES)")
+	fs.StringVar(&cfg.Static, "static", os.Getenv("GOWRITER_STATIC"), "diretório dos arquivos estáticos; vazio usa os embutidos (GOWRITER_STATIC)")
+	fs.BoolVar(&cfg.Dev, "dev", os.Getenv("GOWRITER_DEV") != "", "relê templates e estáticos do disco a cada requisição (GOWRITER_DEV)")
+	retention, err := time.ParseDuration(envOr("GOWRITER_TRASH_RETENTION", "720h"))
+	if err != nil {
+		err = fmt.Errorf("GOWRITER_TRASH_RETENTION inválido: %v", err)
+		fmt.Fprintln(fs.Output(), err)
+		return config{}, nil, err
+	}
+	fs.DurationVar(&cfg.Retention, "trash-retention", retention, "tempo que as seções excluídas ficam na lixeira; 0 mantém para sempre (GOWRITER_TRASH_RETENTION)")
+	if err := fs.Parse(args); err != nil {
+		return config{}, nil, err
+	}
+	return cfg, fs.Args(), nil
+}
+
+// newRequestID gera um ID curto e aleatório para a requisição
+func newRequestID() string {
+	b := make([]byte, 8)
+	if _, err := rand.Read(b); err != nil {
+		panic(err)
+	}
+	return hex.EncodeToString(b)
+}
+
+// requestInfo acompanha a requisição pelos middlewares. O usuário é
+// preenchido pelo Auth, que roda depois do log.
+type requestInfo struct {
+	ID   string
+	User string
+}
+
+type requestInfoKey struct{}
+
+// requestInfoFrom retorna os dados de log da requisição, ou nil
+func requestInfoFrom(r *http.Request) *requestInfo {
+	info, _ := r.Context().Value(requestInfoKey{}).(*requestInfo)
+	return info
+}
+
+// responseRecorder guarda status e tamanho da resposta para os logs e a
+// auditoria
+type responseRecorder struct {
+	http.ResponseWriter
+	status int
+	bytes  int
+}
+
+func (rec *responseRecorder) WriteHeader(status int) {
+	rec.status = status
+	rec.ResponseWriter.WriteHeader(status)
+}
+
+func (rec *responseRecorder) Write(b []byte) (int, error) {
+	n, err := rec.ResponseWriter.Write(b)
+	rec.bytes += n
+	return n, err
+}
+
+func (rec *responseRecorder) Unwrap() http.ResponseWriter {
+	return rec.ResponseWriter
+}
+
+// logRequests registra cada requisição com um ID, repassado no cabeçalho
+// X-Request-ID. Um ID válido vindo de um proxy é reaproveitado.
+func logRequests(logger *slog.Logger, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		info := &requestInfo{ID: r.Header.Get(requestIDHeader)}
+		if !validRequestID.MatchString(info.ID) {
+			info.ID = newRequestID()
+		}
+		w.Header().Set(requestIDHeader, info.ID)
+
+		rec := &responseRecorder{ResponseWriter: w, status: http.StatusOK}
+		r = r.WithContext(context.WithValue(r.Context(), requestInfoKey{}, info))
+		next.ServeHTTP(rec, r)
+
+		level := slog.LevelInfo
+		if rec.status >= http.StatusInternalServerError {
+			level = slog.LevelError
+		}
+		logger.LogAttrs(r.Context(), level, "requisição",
+			slog.String("id", info.ID),
+			slog.String("method", r.Method),
+			slog.String("path", r.URL.RequestURI()),
+			slog.Int("status", rec.status),
+			slog.Int("bytes", rec.bytes),
+			slog.Duration("duration", time.Since(start)),
+			slog.String("user", info.User),
+			slog.String("remote", r.RemoteAddr),
+		)
+	})
+}
+
+// routes registra as rotas da interface web e da API
+func routes(ws *Workspace, auth *Auth) *http.ServeMux {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/", handleIndex(ws))
+	mux.HandleFunc("/api/documents", handleDocuments(ws))
+	mux.HandleFunc("/login", auth.handleLogin)
+	mux.HandleFunc("/logout", auth.handleLogout)
+	mux.HandleFunc("/api/me", handleMe)
+	mux.HandleFunc("/api/documents/{docID}", requireRole(RoleEditor, handleDocument(ws), http.MethodDelete))
+	mux.HandleFunc("/api/documents/{docID}/sections", withDocument(ws, handleSections))
+	mux.HandleFunc("/api/documents/{docID}/sections/{id}", requireRole(RoleEditor, withDocument(ws, handleSection), http.MethodDelete))
+	mux.HandleFunc("/api/documents/{docID}/sections/reorder", requireRole(RoleEditor, withDocument(ws, handleReorder)))
+	mux.HandleFunc("/api/documents/{docID}/sections/{id}/move", requireRole(RoleEditor, withDocument(ws, handleMove)))
+	mux.HandleFunc("/api/documents/{docID}/sections/{id}/revisions", withDocument(ws, handleRevisions))
+	mux.HandleFunc("/api/documents/{docID}/sections/{id}/revisions/{rev}", withDocument(ws, handleRevision))
+	mux.HandleFunc("/api/documents/{docID}/sections/{id}/revisions/{rev}/restore", withDocument(ws, handleRestore))
+	mux.HandleFunc("/api/documents/{docID}/sections/{id}/diff", withDocument(ws, handleDiff))
+	mux.HandleFunc("/api/documents/{docID}/events", withDocument(ws, handleEvents))
+	mux.HandleFunc("/api/documents/{docID}/build", withDocument(ws, handleBuild))
+	mux.HandleFunc("/api/documents/{docID}/export", withDocument(ws, handleExport))
+	mux.HandleFunc("/api/documents/{docID}/cover", withDocument(ws, handleCover))
+	mux.HandleFunc("/api/documents/{docID}/trash/{id}", requireRole(RoleEditor, withDocument(ws, handleTrashItem)))
+	mux.HandleFunc("/api/documents/{docID}/trash/{id}/restore", requireRole(RoleEditor, withDocument(ws, handleTrashRestore)))
+	mux.HandleFunc("/api/trash", handleTrash(ws))
+	mux.HandleFunc("/api/assets", handleAssets(ws))
+	mux.HandleFunc("/assets/{name}", handleAsset(ws))
+	mux.HandleFunc("/api/search", handleSearch(ws))
+	mux.HandleFunc("/api/preview", handlePreview)
+	mux.HandleFunc("/api/preview/highlight.css", handleHighlightCSS)
+
+	// Servir arquivos estáticos
+	mux.Handle("/static/", http.StripPrefix("/static/", web.staticHandler()))
+	return mux
+}
+
+// serve atende em cfg.Addr até receber SIGINT ou SIGTERM. Um segundo
+// sinal encerra o processo na hora.
+func serve(cfg config, ws *Workspace, auth *Auth, logger *slog.Logger) error {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	context.AfterFunc(ctx, stop)
+
+	ln, err := net.Listen("tcp", cfg.Addr)
+	if err != nil {
+		return fmt.Errorf("erro ao iniciar servidor: %v", err)
+	}
+	return runServer(ctx, ln, cfg, ws, auth, logger)
+}
+
+// runServer atende em ln até ctx ser cancelado. Na parada, as requisições
+// em andamento terminam, os streams de eventos são fechados e os
+// manifestos dos documentos são gravados antes de retornar, mesmo que o
+// servidor tenha parado com erro.
+func runServer(ctx context.Context, ln net.Listener, cfg config, ws *Workspace, auth *Auth, logger *slog.Logger) error {
+	// cancelado no Shutdown, para que handlers de longa duração (SSE)
+	// percebam a parada pelo contexto da requisição
+	base, cancelBase := context.WithCancel(context.Background())
+	defer cancelBase()
+
+	srv := &http.Server{
+		Handler:           logRequests(logger, auth.Middleware(routes(ws, auth))),
+		ReadHeaderTimeout: readHeaderTimeout,
+		ReadTimeout:       readTimeout,
+		IdleTimeout:       idleTimeout,
+		ErrorLog:          slog.NewLogLogger(logger.Handler(), slog.LevelWarn),
+		BaseContext:       func(net.Listener) context.Context { return base },
+	}
+	srv.RegisterOnShutdown(cancelBase)
+
+	if cfg.Retention > 0 {
+		go ws.purgeTrashLoop(ctx, cfg.Retention, logger)
+	}
+
+	errc := make(chan error, 1)
+	go func() {
+		logger.Info("servidor iniciado", "addr", ln.Addr().String(), "workspace", ws.Root, "dev", web.dev)
+		errc <- srv.Serve(ln)
+	}()
+
+	var errs []error
+	select {
+	case err := <-errc:
+		errs = append(errs, fmt.Errorf("erro no servidor: %v", err))
+	case <-ctx.Done():
+		logger.Info("encerrando servidor", "timeout", shutdownTimeout)
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+		defer cancel()
+
+		err := srv.Shutdown(shutdownCtx)
+		if errors.Is(err, context.DeadlineExceeded) {
+			logger.Warn("requisições ainda em andamento ao fim do prazo; forçando encerramento")
+			srv.Close()
+		} else if err != nil {
+			errs = append(errs, fmt.Errorf("erro ao encerrar servidor: %v", err))
+		}
+	}
+
+	if err := ws.Flush(); err != nil {
+		errs = append(errs, fmt.Errorf("erro ao gravar documentos: %v", err))
+	}
+	if len(errs) > 0 {
+		return errors.Join(errs...)
+	}
+	logger.Info("servidor encerrado")
+	return nil
+}
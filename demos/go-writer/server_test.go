@@ -0,0 +1,204 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseConfig(t *testing.T) {
+	defaults := config{Addr: ":8080", Root: ".", Retention: 720 * time.Hour}
+
+	tests := []struct {
+		name string
+		env  map[string]string
+		args []string
+		want config
+		rest []string
+	}{
+		{
+			name: "padrões",
+			want: defaults,
+		},
+		{
+			name: "ambiente",
+			env: map[string]string{
+				"GOWRITER_ADDR":            ":9000",
+				"GOWRITER_WORKSPACE":       "/srv/livros",
+				"GOWRITER_TEMPLATES":       "tpl",
+				"GOWRITER_STATIC":          "st",
+				"GOWRITER_DEV":             "1",
+				"GOWRITER_TRASH_RETENTION": "24h",
+			},
+			want: config{Addr: ":9000", Root: "/srv/livros", Templates: "tpl", Static: "st", Dev: true, Retention: 24 * time.Hour},
+		},
+		{
+			name: "flags têm precedência sobre o ambiente",
+			env: map[string]string{
+				"GOWRITER_ADDR":            ":9000",
+				"GOWRITER_DEV":             "1",
+				"GOWRITER_TRASH_RETENTION": "24h",
+			},
+			args: []string{"-addr", ":7000", "-dev=false", "-trash-retention", "0"},
+			want: config{Addr: ":7000", Root: "."},
+		},
+		{
+			name: "subcomando",
+			args: []string{"-workspace", "livro", "user", "add", "ana", "editor"},
+			want: config{Addr: ":8080", Root: "livro", Retention: 720 * time.Hour},
+			rest: []string{"user", "add", "ana", "editor"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for _, key := range []string{"GOWRITER_ADDR", "GOWRITER_WORKSPACE", "GOWRITER_TEMPLATES", "GOWRITER_STATIC", "GOWRITER_DEV", "GOWRITER_TRASH_RETENTION"} {
+				t.Setenv(key, tt.env[key])
+			}
+			cfg, rest, err := parseConfig(tt.args)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if cfg != tt.want {
+				t.Errorf("config = %+v, esperado %+v", cfg, tt.want)
+			}
+			if !slices.Equal(rest, tt.rest) {
+				t.Errorf("argumentos restantes = %q, esperado %q", rest, tt.rest)
+			}
+		})
+	}
+
+	t.Run("valores inválidos", func(t *testing.T) {
+		t.Setenv("GOWRITER_TRASH_RETENTION", "um mês")
+		if _, _, err := parseConfig(nil); err == nil {
+			t.Error("retenção inválida no ambiente aceita")
+		}
+		t.Setenv("GOWRITER_TRASH_RETENTION", "")
+		if _, _, err := parseConfig([]string{"-trash-retention", "sempre"}); err == nil {
+			t.Error("retenção inválida na flag aceita")
+		}
+	})
+}
+
+func TestLogRequestsRequestID(t *testing.T) {
+	var logs bytes.Buffer
+	logger := slog.New(slog.NewJSONHandler(&logs, nil))
+
+	var seen string
+	handler := logRequests(logger, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		seen = requestInfoFrom(r).ID
+		if r.URL.Path == "/falha" {
+			http.Error(w, "falhou", http.StatusInternalServerError)
+		}
+	}))
+	generated := regexp.MustCompile(`^[0-9a-f]{16}$`)
+
+	tests := []struct {
+		name     string
+		path     string
+		incoming string
+		reuse    bool
+		level    string
+	}{
+		{"ID do proxy reaproveitado", "/", "proxy-1.2_3", true, "INFO"},
+		{"sem ID", "/", "", false, "INFO"},
+		{"ID com espaço", "/", "a b", false, "INFO"},
+		{"ID com quebra de linha", "/", "a\nid=falso", false, "INFO"},
+		{"ID longo demais", "/", strings.Repeat("a", 65), false, "INFO"},
+		{"erro do servidor", "/falha", "", false, "ERROR"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			logs.Reset()
+			req := httptest.NewRequest(http.MethodGet, tt.path, nil)
+			if tt.incoming != "" {
+				req.Header.Set(requestIDHeader, tt.incoming)
+			}
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, req)
+
+			id := rec.Header().Get(requestIDHeader)
+			if id != seen {
+				t.Errorf("cabeçalho %q difere do ID da requisição %q", id, seen)
+			}
+			if tt.reuse && id != tt.incoming {
+				t.Errorf("ID = %q, esperado o do proxy %q", id, tt.incoming)
+			}
+			if !tt.reuse && !generated.MatchString(id) {
+				t.Errorf("ID gerado = %q", id)
+			}
+
+			var entry struct {
+				Level  string
+				Msg    string
+				ID     string
+				Status int
+			}
+			if err := json.Unmarshal(logs.Bytes(), &entry); err != nil {
+				t.Fatalf("log %q: %v", logs.String(), err)
+			}
+			if entry.ID != id || entry.Level != tt.level || entry.Msg != "requisição" {
+				t.Errorf("log = %+v", entry)
+			}
+			if entry.Status != rec.Code {
+				t.Errorf("status no log = %d, na resposta %d", entry.Status, rec.Code)
+			}
+		})
+	}
+}
+
+// Na parada os streams de eventos são fechados e os manifestos gravados
+func TestRunServerShutdown(t *testing.T) {
+	ts := newTestServer(t)
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+
+	done := make(chan error, 1)
+	go func() { done <- runServer(ctx, ln, config{}, ts.ws, ts.auth, logger) }()
+
+	live := &testServer{Server: &httptest.Server{URL: "http://" + ln.Addr().String()}, ws: ts.ws, doc: ts.doc, auth: ts.auth}
+	_, frames := live.login(t, "reader").subscribe()
+
+	manifest := filepath.Join(ts.doc.Dir, manifestFile)
+	if err := os.Remove(manifest); err != nil {
+		t.Fatal(err)
+	}
+	cancel()
+
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Fatalf("runServer: %v", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("servidor não parou com o stream de eventos aberto")
+	}
+	select {
+	case _, ok := <-frames:
+		if ok {
+			t.Error("evento inesperado na parada")
+		}
+	case <-time.After(time.Second):
+		t.Error("stream de eventos continua aberto")
+	}
+	if _, err := os.Stat(manifest); err != nil {
+		t.Errorf("manifesto não gravado na parada: %v", err)
+	}
+}
@@ -0,0 +1,195 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"html/template"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	xhtml "golang.org/x/net/html"
+)
+
+// siteTemplates é o subdiretório dos templates com o layout e os
+// arquivos estáticos do site exportado
+const siteTemplates = "site"
+
+// siteDir é o diretório do site dentro de output/
+const siteDir = "site"
+
+// sitePage é uma página do site: uma seção do documento
+type sitePage struct {
+	Title string
+	File  string
+	Depth int
+	HTML  template.HTML
+}
+
+// sitePageData alimenta templates/site/page.html. Current é nil na capa.
+type sitePageData struct {
+	Title   string
+	Pages   []sitePage
+	Current *sitePage
+	Prev    *sitePage
+	Next    *sitePage
+}
+
+// searchEntry é um item de search-index.json
+type searchEntry struct {
+	URL   string `json:"url"`
+	Title string `json:"title"`
+	Text  string `json:"text"`
+}
+
+// ExportSite gera em output/site um site estático com uma página por
+// seção, sumário lateral, navegação anterior/próxima, um índice de busca
+// em JSON e os anexos citados. Os links são relativos, então o diretório pode ser publicado
+// como está (no GitHub Pages, por exemplo).
+func (d *Document) ExportSite() (string, error) {
+	tmpl, err := web.page(path.Join(siteTemplates, "page.html"))
+	if err != nil {
+		return "", err
+	}
+
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+
+	out := filepath.Join(d.Dir, outputDir)
+	tmp, err := os.MkdirTemp(out, ".site-*")
+	if err != nil {
+		return "", fmt.Errorf("erro ao gerar site: %v", err)
+	}
+	defer os.RemoveAll(tmp)
+
+	if err := d.writeSite(tmp, tmpl); err != nil {
+		return "", fmt.Errorf("erro ao gerar site: %v", err)
+	}
+	if err := os.Chmod(tmp, 0755); err != nil {
+		return "", err
+	}
+
+	// troca o site anterior pelo novo de uma vez
+	path := filepath.Join(out, siteDir)
+	if err := os.RemoveAll(path); err != nil {
+		return "", fmt.Errorf("erro ao remover site anterior: %v", err)
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		return "", fmt.Errorf("erro ao salvar site %s: %v", path, err)
+	}
+	return path, nil
+}
+
+func (d *Document) writeSite(dir string, tmpl *template.Template) error {
+	sections := d.sortedSections()
+	pages := make([]sitePage, len(sections))
+	index := make([]searchEntry, len(sections))
+
+	depths := d.depths()
+	files := newSlugger()
+	files.slug("index")
+	for i, s := range sections {
+		rendered, err := RenderMarkdown(s.Content)
+		if err != nil {
+			return fmt.Errorf("seção %s: %v", s.ID, err)
+		}
+
+		name := files.slug(foldTerm(s.Title))
+		if strings.Trim(name, "-") == "" {
+			name = fmt.Sprintf("secao-%d", i+1)
+		}
+		pages[i] = sitePage{Title: s.Title, File: name + ".html", Depth: depths[s.ID], HTML: template.HTML(rendered)}
+		index[i] = searchEntry{URL: pages[i].File, Title: s.Title, Text: plainText(rendered)}
+	}
+
+	write := func(name string, data sitePageData) error {
+		var buf bytes.Buffer
+		if err := tmpl.Execute(&buf, data); err != nil {
+			return err
+		}
+		return os.WriteFile(filepath.Join(dir, name), buf.Bytes(), 0644)
+	}
+
+	if err := write("index.html", sitePageData{Title: d.Title, Pages: pages}); err != nil {
+		return err
+	}
+	for i := range pages {
+		data := sitePageData{Title: d.Title, Pages: pages, Current: &pages[i]}
+		if i > 0 {
+			data.Prev = &pages[i-1]
+		}
+		if i+1 < len(pages) {
+			data.Next = &pages[i+1]
+		}
+		if err := write(pages[i].File, data); err != nil {
+			return err
+		}
+	}
+
+	searchIndex, err := json.Marshal(index)
+	if err != nil {
+		return err
+	}
+	css, err := web.readFile(path.Join(siteTemplates, "style.css"))
+	if err != nil {
+		return err
+	}
+	highlight, err := highlightCSS()
+	if err != nil {
+		return err
+	}
+	js, err := web.readFile(path.Join(siteTemplates, "search.js"))
+	if err != nil {
+		return err
+	}
+
+	assets := map[string][]byte{
+		"search-index.json": searchIndex,
+		"style.css":         append(append(css, '\n'), highlight...),
+		"search.js":         js,
+		// sem isto o GitHub Pages passa o diretório pelo Jekyll
+		".nojekyll": nil,
+	}
+	for name, data := range assets {
+		if err := os.WriteFile(filepath.Join(dir, name), data, 0644); err != nil {
+			return err
+		}
+	}
+	return d.copyAssets(dir)
+}
+
+// plainText extrai o texto do HTML renderizado, para o índice de busca
+func plainText(rendered []byte) string {
+	var b strings.Builder
+	z := xhtml.NewTokenizer(bytes.NewReader(rendered))
+	for {
+		switch z.Next() {
+		case xhtml.ErrorToken:
+			return strings.Join(strings.Fields(b.String()), " ")
+		case xhtml.TextToken:
+			b.Write(z.Text())
+			b.WriteByte(' ')
+		}
+	}
+}
+
+// dirSize soma o tamanho dos arquivos exportados; path pode ser um
+// arquivo ou um diretório
+func dirSize(path string) (int64, error) {
+	var size int64
+	err := filepath.WalkDir(path, func(_ string, entry fs.DirEntry, err error) error {
+		if err != nil || entry.IsDir() {
+			return err
+		}
+		info, err := entry.Info()
+		if err != nil {
+			return err
+		}
+		size += info.Size()
+		return nil
+	})
+	return size, err
+}
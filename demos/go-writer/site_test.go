@@ -0,0 +1,159 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+
+	xhtml "golang.org/x/net/html"
+)
+
+// pageLinks lista os href e src de uma página HTML
+func pageLinks(t *testing.T, page []byte) []string {
+	t.Helper()
+	var links []string
+	z := xhtml.NewTokenizer(bytes.NewReader(page))
+	for {
+		switch z.Next() {
+		case xhtml.ErrorToken:
+			return links
+		case xhtml.StartTagToken, xhtml.SelfClosingTagToken:
+			for {
+				key, val, more := z.TagAttr()
+				if k := string(key); k == "href" || k == "src" {
+					links = append(links, string(val))
+				}
+				if !more {
+					break
+				}
+			}
+		}
+	}
+}
+
+func TestExportSite(t *testing.T) {
+	ws := NewWorkspace(t.TempDir())
+	if err := initializeWorkspace(ws); err != nil {
+		t.Fatal(err)
+	}
+	doc, err := ws.Create("Livro de Teste")
+	if err != nil {
+		t.Fatal(err)
+	}
+	asset, err := ws.SaveAsset(testPNG(t), "diagrama.png")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	part, err := doc.AddSection("Parte 1", "", "", "teste")
+	if err != nil {
+		t.Fatal(err)
+	}
+	chapter, err := doc.AddSection("Capítulo 1: Olá & <mundo>", "Veja "+asset.Markdown, part.ID, "teste")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := doc.AddSection("Intro", "Primeira.", chapter.ID, "teste"); err != nil {
+		t.Fatal(err)
+	}
+	// títulos repetidos, ou iguais à capa, não podem sobrescrever páginas
+	if _, err := doc.AddSection("Intro", "Segunda.", "", "teste"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := doc.AddSection("Index", "Não é a capa.", "", "teste"); err != nil {
+		t.Fatal(err)
+	}
+
+	dir, err := doc.ExportSite()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if dir != filepath.Join(doc.Dir, outputDir, siteDir) {
+		t.Fatalf("site gerado em %s", dir)
+	}
+
+	pages := []string{"parte-1.html", "capitulo-1-ola--mundo.html", "intro.html", "intro-1.html", "index-1.html"}
+	var files []string
+	err = filepath.WalkDir(dir, func(path string, entry os.DirEntry, err error) error {
+		if err == nil && !entry.IsDir() {
+			rel, _ := filepath.Rel(dir, path)
+			files = append(files, filepath.ToSlash(rel))
+		}
+		return err
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := append([]string{
+		".nojekyll", "assets/" + asset.Name, "index.html",
+		"search-index.json", "search.js", "style.css",
+	}, pages...)
+	slices.Sort(files)
+	slices.Sort(want)
+	if !slices.Equal(files, want) {
+		t.Fatalf("arquivos do site:\n%q\nesperados:\n%q", files, want)
+	}
+
+	// todo link relativo de toda página aponta para um arquivo do site
+	for _, name := range append([]string{"index.html"}, pages...) {
+		page, err := os.ReadFile(filepath.Join(dir, name))
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, link := range pageLinks(t, page) {
+			if strings.HasPrefix(link, "#") || strings.Contains(link, "://") {
+				continue
+			}
+			if !slices.Contains(files, link) {
+				t.Errorf("%s: link quebrado %q", name, link)
+			}
+		}
+	}
+
+	// navegação anterior/próxima na ordem do documento, e a imagem com o
+	// caminho relativo copiado
+	chapterPage, err := os.ReadFile(filepath.Join(dir, pages[1]))
+	if err != nil {
+		t.Fatal(err)
+	}
+	links := pageLinks(t, chapterPage)
+	for _, link := range []string{pages[0], pages[2], "assets/" + asset.Name} {
+		if !slices.Contains(links, link) {
+			t.Errorf("página do capítulo sem link para %s: %q", link, links)
+		}
+	}
+	if !bytes.Contains(chapterPage, []byte(`<li class="depth-1 active">`)) ||
+		!bytes.Contains(chapterPage, []byte("<h1>Capítulo 1: Olá &amp; &lt;mundo&gt;</h1>")) {
+		t.Errorf("página do capítulo sem o título ou o sumário com a profundidade")
+	}
+
+	var index []searchEntry
+	data, err := os.ReadFile(filepath.Join(dir, "search-index.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := json.Unmarshal(data, &index); err != nil {
+		t.Fatal(err)
+	}
+	if len(index) != len(pages) || index[3] != (searchEntry{URL: "intro-1.html", Title: "Intro", Text: "Segunda."}) {
+		t.Errorf("search-index.json = %+v", index)
+	}
+
+	// exportar de novo troca o site inteiro, sem deixar páginas antigas
+	if err := os.WriteFile(filepath.Join(dir, "antiga.html"), nil, 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := doc.ExportSite(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "antiga.html")); !os.IsNotExist(err) {
+		t.Errorf("página antiga continua no site: %v", err)
+	}
+	if temps, _ := filepath.Glob(filepath.Join(doc.Dir, outputDir, ".site-*")); len(temps) > 0 {
+		t.Errorf("temporários deixados para trás: %v", temps)
+	}
+}
@@ -1,36 +1,151 @@
 document.addEventListener('DOMContentLoaded', function() {
+    const docID = document.body.dataset.docId;
+    const api = `/api/documents/${docID}`;
     const sectionsContainer = document.getElementById('sections-container');
     const addSectionBtn = document.getElementById('add-section');
+    const addSubsectionBtn = document.getElementById('add-subsection');
     const saveSectionBtn = document.getElementById('save-section');
+    const deleteSectionBtn = document.getElementById('delete-section');
+    const buildDocumentBtn = document.getElementById('build-document');
+    const exportEpubBtn = document.getElementById('export-epub');
+    const exportSiteBtn = document.getElementById('export-site');
+    const coverInput = document.getElementById('cover-input');
+    const assetInput = document.getElementById('asset-input');
+    const documentSelect = document.getElementById('document-select');
+    const newDocumentBtn = document.getElementById('new-document');
+    const renameDocumentBtn = document.getElementById('rename-document');
+    const deleteDocumentBtn = document.getElementById('delete-document');
     const sectionTitle = document.getElementById('section-title');
     const sectionContent = document.getElementById('section-content');
+    const sectionPreview = document.getElementById('section-preview');
+    const historyBtn = document.getElementById('section-history');
+    const revisionsPanel = document.getElementById('revisions-panel');
+    const lintPanel = document.getElementById('lint-panel');
+    const showTrashBtn = document.getElementById('show-trash');
+    const trashPanel = document.getElementById('trash-panel');
+    const searchInput = document.getElementById('search-input');
+    const searchResults = document.getElementById('search-results');
 
     let sections = [];
     let currentSection = null;
+    let previewTimer = null;
+
+    const role = document.body.dataset.role;
+    const canReorder = role === 'editor';
+
+    // Toda requisição que altera dados leva o token CSRF da sessão; o
+    // autor das alterações é o usuário logado
+    const csrfToken = document.querySelector('meta[name="csrf-token"]').content;
+    const nativeFetch = window.fetch.bind(window);
+    window.fetch = (url, options = {}) => {
+        const method = (options.method || 'GET').toUpperCase();
+        if (method !== 'GET' && method !== 'HEAD') {
+            options = { ...options, headers: { ...options.headers, 'X-CSRF-Token': csrfToken } };
+        }
+        return nativeFetch(url, options);
+    };
+
+    // Atualizar o preview renderizado no servidor enquanto o usuário digita
+    function schedulePreview() {
+        clearTimeout(previewTimer);
+        previewTimer = setTimeout(renderPreview, 300);
+    }
+
+    async function renderPreview() {
+        try {
+            const response = await fetch('/api/preview', {
+                method: 'POST',
+                headers: {
+                    'Content-Type': 'application/json',
+                },
+                body: JSON.stringify({ content: sectionContent.value }),
+            });
+            if (!response.ok) throw new Error('Erro ao gerar preview');
+
+            const result = await response.json();
+            sectionPreview.innerHTML = result.html;
+        } catch (error) {
+            console.error('Erro:', error);
+        }
+    }
+
+    sectionContent.addEventListener('input', schedulePreview);
+
+    // Drag and drop na árvore: soltar na parte de cima ou de baixo de uma
+    // seção coloca a arrastada antes ou depois dela; no meio, dentro dela.
+    // A seção leva junto todas as suas subseções.
+    let dragId = null;
+
+    function clearDropMarks() {
+        sectionsContainer.querySelectorAll('.drop-before, .drop-after, .drop-inside')
+            .forEach(el => el.classList.remove('drop-before', 'drop-after', 'drop-inside'));
+    }
+
+    function dropZone(target, e) {
+        const box = target.getBoundingClientRect();
+        const y = (e.clientY - box.top) / box.height;
+        if (y < 0.25) return 'before';
+        if (y > 0.75) return 'after';
+        return 'inside';
+    }
 
-    // Configurar drag and drop
     sectionsContainer.addEventListener('dragover', e => {
+        if (!dragId) return;
+        const target = e.target.closest('.section-item');
+        clearDropMarks();
+        if (!target || target.classList.contains('dragging')) return;
         e.preventDefault();
-        const draggingItem = document.querySelector('.dragging');
-        const siblings = [...sectionsContainer.querySelectorAll('.section-item:not(.dragging)')];
-        
-        const nextSibling = siblings.find(sibling => {
-            const box = sibling.getBoundingClientRect();
-            return e.clientY <= box.top + box.height / 2;
-        });
+        target.classList.add(`drop-${dropZone(target, e)}`);
+    });
 
-        if (nextSibling) {
-            sectionsContainer.insertBefore(draggingItem, nextSibling);
-        } else {
-            sectionsContainer.appendChild(draggingItem);
+    sectionsContainer.addEventListener('drop', async e => {
+        const target = e.target.closest('.section-item');
+        if (!dragId || !target || target.classList.contains('dragging')) return;
+        e.preventDefault();
+
+        const zone = dropZone(target, e);
+        const parentID = zone === 'inside' ? target.dataset.id : target.dataset.parent;
+        const siblings = childIDs(parentID).filter(id => id !== dragId);
+        let index = siblings.length;
+        if (zone === 'before') index = siblings.indexOf(target.dataset.id);
+        if (zone === 'after') index = siblings.indexOf(target.dataset.id) + 1;
+
+        try {
+            const response = await fetch(`${api}/sections/${dragId}/move`, {
+                method: 'POST',
+                headers: { 'Content-Type': 'application/json' },
+                body: JSON.stringify({ parentID, index }),
+            });
+            if (!response.ok) throw new Error(await response.text());
+            renderSections(await response.json());
+        } catch (error) {
+            console.error('Erro:', error);
+            alert('Erro ao mover seção');
+            loadSections();
         }
     });
 
-    // Adicionar nova seção
-    addSectionBtn.addEventListener('click', () => {
+    // Adicionar nova seção na raiz, ou dentro da seção aberta
+    let newParentID = '';
+
+    function startNewSection(parentID) {
+        newParentID = parentID;
+        showWarnings([]);
         sectionTitle.value = '';
         sectionContent.value = '';
+        sectionPreview.innerHTML = '';
         currentSection = null;
+        sectionTitle.focus();
+    }
+
+    addSectionBtn.addEventListener('click', () => startNewSection(''));
+
+    addSubsectionBtn.addEventListener('click', () => {
+        if (!currentSection) {
+            alert('Abra a seção onde a subseção será criada');
+            return;
+        }
+        startNewSection(currentSection.ID);
     });
 
     // Salvar seção
@@ -38,27 +153,60 @@ document.addEventListener('DOMContentLoaded', function() {
         const title = sectionTitle.value.trim();
         const content = sectionContent.value.trim();
         
-        if (!title || !content) {
-            alert('Por favor, preencha título e conteúdo');
+        // partes e capítulos podem ser só um título que agrupa subseções
+        if (!title) {
+            alert('Por favor, preencha o título');
             return;
         }
 
+        // Seção existente é atualizada com PUT, nova seção é criada com POST
+        const url = currentSection ? `${api}/sections/${currentSection.ID}` : `${api}/sections`;
+        const method = currentSection ? 'PUT' : 'POST';
+        const headers = {
+            'Content-Type': 'application/json',
+        };
+        if (currentSection) {
+            // versão que estamos editando; o servidor recusa se alguém salvou antes
+            headers['If-Match'] = `"${currentSection.Version}"`;
+        }
+
         try {
-            const response = await fetch('/api/sections', {
-                method: 'POST',
-                headers: {
-                    'Content-Type': 'application/json',
-                },
-                body: JSON.stringify({ title, content }),
+            const response = await fetch(url, {
+                method,
+                headers,
+                body: JSON.stringify(currentSection ? { title, content } : { title, content, parentID: newParentID }),
             });
 
+            if (response.status === 409) {
+                const serverCopy = await response.json();
+                replaceSectionInList(serverCopy);
+                if (confirm('Outra pessoa salvou esta seção antes de você. Carregar a versão do servidor? (Cancelar mantém seu texto para salvar por cima)')) {
+                    sectionTitle.value = serverCopy.Title;
+                    sectionContent.value = serverCopy.Content;
+                    renderPreview();
+                }
+                currentSection = serverCopy;
+                return;
+            }
             if (!response.ok) throw new Error('Erro ao salvar seção');
 
-            const section = await response.json();
-            addSectionToList(section);
-            
+            const { Warnings: warnings, ...section } = await response.json();
+            if (currentSection) {
+                replaceSectionInList(section);
+            } else {
+                addSectionToList(section);
+            }
+
+            // com avisos de estilo a seção continua aberta para correção
+            showWarnings(warnings);
+            if (warnings.length > 0) {
+                currentSection = section;
+                return;
+            }
+
             sectionTitle.value = '';
             sectionContent.value = '';
+            sectionPreview.innerHTML = '';
             currentSection = null;
         } catch (error) {
             console.error('Erro:', error);
@@ -66,67 +214,547 @@ document.addEventListener('DOMContentLoaded', function() {
         }
     });
 
-    function addSectionToList(section) {
+    // Lixeira: seções excluídas podem voltar ao lugar de onde saíram
+    showTrashBtn.addEventListener('click', async () => {
+        if (trashPanel.childElementCount > 0) {
+            trashPanel.innerHTML = '';
+            return;
+        }
+
+        try {
+            const response = await fetch(`/api/trash?doc=${encodeURIComponent(docID)}`);
+            if (!response.ok) throw new Error('Erro ao carregar lixeira');
+
+            const items = await response.json();
+            if (items.length === 0) {
+                trashPanel.textContent = 'A lixeira está vazia';
+                return;
+            }
+            items.forEach(trashItem => {
+                const id = trashItem.Section.ID;
+                const item = document.createElement('div');
+                item.className = 'revision-item';
+                item.innerHTML = `
+                    <span class="revision-label"></span>
+                    <button class="btn btn-small requires-editor" data-action="restore">Restaurar</button>
+                    <button class="btn btn-small btn-danger requires-editor" data-action="purge">Excluir</button>
+                `;
+                item.querySelector('.revision-label').textContent =
+                    `${trashItem.Section.Title} · ${new Date(trashItem.DeletedAt).toLocaleString()} · ${trashItem.DeletedBy}`;
+
+                item.querySelector('[data-action="restore"]').addEventListener('click', async () => {
+                    const restoreResponse = await fetch(`${api}/trash/${id}/restore`, { method: 'POST' });
+                    if (!restoreResponse.ok) {
+                        alert('Erro ao restaurar seção');
+                        return;
+                    }
+                    item.remove();
+                    fetchSections().catch(error => console.error('Erro:', error));
+                });
+
+                item.querySelector('[data-action="purge"]').addEventListener('click', async () => {
+                    if (!confirm('Excluir definitivamente esta seção e seu histórico?')) return;
+                    const purgeResponse = await fetch(`${api}/trash/${id}`, { method: 'DELETE' });
+                    if (!purgeResponse.ok) {
+                        alert('Erro ao excluir seção');
+                        return;
+                    }
+                    item.remove();
+                });
+
+                trashPanel.appendChild(item);
+            });
+        } catch (error) {
+            console.error('Erro:', error);
+            alert('Erro ao carregar lixeira');
+        }
+    });
+
+    // Avisos do lint retornados ao salvar; clicar seleciona a linha
+    function showWarnings(warnings) {
+        lintPanel.innerHTML = '';
+        warnings.forEach(w => {
+            const item = document.createElement('div');
+            item.className = 'lint-warning';
+            item.textContent = w.Line > 0 ? `Linha ${w.Line}: ${w.Message}` : w.Message;
+            item.title = w.Rule;
+            item.addEventListener('click', () => selectLine(w.Line));
+            lintPanel.appendChild(item);
+        });
+    }
+
+    function selectLine(number) {
+        if (number < 1) {
+            sectionTitle.focus();
+            return;
+        }
+        const lines = sectionContent.value.split('\n');
+        let start = 0;
+        for (let i = 0; i < number - 1 && i < lines.length; i++) {
+            start += lines[i].length + 1;
+        }
+        const end = start + (lines[number - 1] || '').length;
+        sectionContent.focus();
+        sectionContent.setSelectionRange(start, end);
+    }
+
+    // Histórico de revisões da seção selecionada
+    historyBtn.addEventListener('click', async () => {
+        if (!currentSection) return;
+        const base = `${api}/sections/${currentSection.ID}`;
+
+        try {
+            const response = await fetch(`${base}/revisions`);
+            if (!response.ok) throw new Error('Erro ao carregar histórico');
+
+            const revisions = await response.json();
+            revisionsPanel.innerHTML = '';
+            revisions.slice().reverse().forEach(rev => {
+                const item = document.createElement('div');
+                item.className = 'revision-item';
+                item.innerHTML = `
+                    <span class="revision-label"></span>
+                    <button class="btn btn-small" data-action="diff">Diff</button>
+                    <button class="btn btn-small requires-author" data-action="restore">Restaurar</button>
+                    <pre class="revision-diff" hidden></pre>
+                `;
+                item.querySelector('.revision-label').textContent =
+                    `#${rev.Number} · ${new Date(rev.Timestamp).toLocaleString()} · ${rev.Author}`;
+
+                item.querySelector('[data-action="diff"]').addEventListener('click', async () => {
+                    const diffResponse = await fetch(`${base}/diff?from=${rev.Number}`);
+                    const pre = item.querySelector('.revision-diff');
+                    pre.textContent = diffResponse.ok ? (await diffResponse.text() || 'Sem diferenças') : 'Erro ao gerar diff';
+                    pre.hidden = false;
+                });
+
+                item.querySelector('[data-action="restore"]').addEventListener('click', async () => {
+                    if (!confirm(`Restaurar a revisão #${rev.Number}?`)) return;
+                    const restoreResponse = await fetch(`${base}/revisions/${rev.Number}/restore`, {
+                        method: 'POST',
+                        headers: { 'If-Match': `"${currentSection.Version}"` },
+                    });
+                    if (restoreResponse.status === 409) {
+                        alert('Outra pessoa alterou esta seção. Revise antes de restaurar.');
+                        const serverCopy = await restoreResponse.json();
+                        replaceSectionInList(serverCopy);
+                        currentSection = serverCopy;
+                        return;
+                    }
+                    if (!restoreResponse.ok) {
+                        alert('Erro ao restaurar revisão');
+                        return;
+                    }
+                    const section = await restoreResponse.json();
+                    replaceSectionInList(section);
+                    currentSection = section;
+                    sectionTitle.value = section.Title;
+                    sectionContent.value = section.Content;
+                    renderPreview();
+                    revisionsPanel.innerHTML = '';
+                });
+
+                revisionsPanel.appendChild(item);
+            });
+        } catch (error) {
+            console.error('Erro:', error);
+            alert('Erro ao carregar histórico');
+        }
+    });
+
+    // Excluir seção selecionada
+    deleteSectionBtn.addEventListener('click', async () => {
+        if (!currentSection) return;
+        if (!confirm(`Mover a seção "${currentSection.Title}" para a lixeira?`)) return;
+
+        try {
+            const response = await fetch(`${api}/sections/${currentSection.ID}`, {
+                method: 'DELETE',
+                headers: { 'If-Match': `"${currentSection.Version}"` },
+            });
+
+            if (response.status === 409) {
+                alert('Outra pessoa alterou esta seção. Revise antes de excluir.');
+                const serverCopy = await response.json();
+                replaceSectionInList(serverCopy);
+                currentSection = serverCopy;
+                return;
+            }
+
+            if (!response.ok) throw new Error('Erro ao excluir seção');
+
+            const element = sectionsContainer.querySelector(`[data-id="${currentSection.ID}"]`);
+            if (element) element.remove();
+
+            sectionTitle.value = '';
+            sectionContent.value = '';
+            sectionPreview.innerHTML = '';
+            currentSection = null;
+        } catch (error) {
+            console.error('Erro:', error);
+            alert('Erro ao excluir seção');
+        }
+    });
+
+    // Gerar o Markdown final do documento
+    buildDocumentBtn.addEventListener('click', async () => {
+        try {
+            const response = await fetch(`${api}/build`, { method: 'POST' });
+            if (!response.ok) throw new Error('Erro ao gerar documento');
+
+            const result = await response.json();
+            alert(`Documento gerado em ${result.path} (${result.sections} seções)`);
+        } catch (error) {
+            console.error('Erro:', error);
+            alert('Erro ao gerar documento');
+        }
+    });
+
+    // Exportar o documento em outro formato (epub, html)
+    async function exportDocument(format, label) {
+        try {
+            const response = await fetch(`${api}/export?format=${format}`, { method: 'POST' });
+            if (!response.ok) throw new Error(await response.text());
+
+            const result = await response.json();
+            alert(`${label} gerado em ${result.path} (${result.sections} seções)`);
+        } catch (error) {
+            console.error('Erro:', error);
+            alert(`Erro ao exportar ${label}`);
+        }
+    }
+
+    exportEpubBtn.addEventListener('click', () => exportDocument('epub', 'EPUB'));
+    exportSiteBtn.addEventListener('click', () => exportDocument('html', 'Site'));
+
+    // Enviar a imagem de capa usada nas exportações
+    coverInput.addEventListener('change', async () => {
+        const file = coverInput.files[0];
+        if (!file) return;
+        try {
+            const response = await fetch(`${api}/cover`, {
+                method: 'PUT',
+                headers: { 'Content-Type': file.type },
+                body: file
+            });
+            if (!response.ok) throw new Error(await response.text());
+            alert('Capa atualizada');
+        } catch (error) {
+            console.error('Erro:', error);
+            alert(`Erro ao enviar capa: ${error.message}`);
+        } finally {
+            coverInput.value = '';
+        }
+    });
+
+    // Enviar um anexo e inserir o Markdown dele na posição do cursor
+    assetInput.addEventListener('change', async () => {
+        const file = assetInput.files[0];
+        if (!file) return;
+        const form = new FormData();
+        form.append('file', file);
+        try {
+            const response = await fetch('/api/assets', { method: 'POST', body: form });
+            if (!response.ok) throw new Error(await response.text());
+
+            const asset = await response.json();
+            const start = sectionContent.selectionStart;
+            const end = sectionContent.selectionEnd;
+            sectionContent.setRangeText(asset.markdown, start, end, 'end');
+            sectionContent.focus();
+            schedulePreview();
+        } catch (error) {
+            console.error('Erro:', error);
+            alert(`Erro ao enviar anexo: ${error.message}`);
+        } finally {
+            assetInput.value = '';
+        }
+    });
+
+    // Trocar de documento
+    documentSelect.addEventListener('change', () => {
+        window.location.href = `/?doc=${encodeURIComponent(documentSelect.value)}`;
+    });
+
+    // Criar novo documento
+    newDocumentBtn.addEventListener('click', async () => {
+        const title = prompt('Título do novo documento');
+        if (!title || !title.trim()) return;
+
+        try {
+            const response = await fetch('/api/documents', {
+                method: 'POST',
+                headers: {
+                    'Content-Type': 'application/json',
+                },
+                body: JSON.stringify({ title: title.trim() }),
+            });
+            if (!response.ok) throw new Error('Erro ao criar documento');
+
+            const doc = await response.json();
+            window.location.href = `/?doc=${encodeURIComponent(doc.ID)}`;
+        } catch (error) {
+            console.error('Erro:', error);
+            alert('Erro ao criar documento');
+        }
+    });
+
+    // Renomear documento atual
+    renameDocumentBtn.addEventListener('click', async () => {
+        const current = documentSelect.options[documentSelect.selectedIndex];
+        const title = prompt('Novo título do documento', current ? current.text : '');
+        if (!title || !title.trim()) return;
+
+        try {
+            const response = await fetch(api, {
+                method: 'PATCH',
+                headers: {
+                    'Content-Type': 'application/json',
+                },
+                body: JSON.stringify({ title: title.trim() }),
+            });
+            if (!response.ok) throw new Error('Erro ao renomear documento');
+
+            window.location.reload();
+        } catch (error) {
+            console.error('Erro:', error);
+            alert('Erro ao renomear documento');
+        }
+    });
+
+    // Excluir documento atual
+    deleteDocumentBtn.addEventListener('click', async () => {
+        if (!confirm('Excluir este documento e todas as suas seções?')) return;
+
+        try {
+            const response = await fetch(api, { method: 'DELETE' });
+            if (!response.ok) throw new Error('Erro ao excluir documento');
+
+            window.location.href = '/';
+        } catch (error) {
+            console.error('Erro:', error);
+            alert('Erro ao excluir documento');
+        }
+    });
+
+    function createSectionElement(section) {
         const sectionElement = document.createElement('div');
         sectionElement.className = 'section-item';
-        sectionElement.draggable = true;
+        sectionElement.draggable = canReorder;
         sectionElement.dataset.id = section.ID;
+        sectionElement.dataset.parent = section.ParentID || '';
         sectionElement.innerHTML = `
-            <h3>${section.Title}</h3>
-            <div class="section-preview">${section.Content.substring(0, 100)}...</div>
+            <h3></h3>
+            <div class="section-preview"></div>
         `;
+        sectionElement.querySelector('h3').textContent = section.Title;
+        sectionElement.querySelector('.section-preview').textContent =
+            section.Content ? `${section.Content.substring(0, 100)}...` : '';
 
-        sectionElement.addEventListener('dragstart', () => {
-            sectionElement.classList.add('dragging');
+        sectionElement.addEventListener('dragstart', e => {
+            e.stopPropagation();
+            dragId = section.ID;
+            subtreeElements(section.ID).forEach(el => el.classList.add('dragging'));
         });
 
         sectionElement.addEventListener('dragend', () => {
-            sectionElement.classList.remove('dragging');
-            updateSectionsOrder();
+            dragId = null;
+            clearDropMarks();
+            sectionsContainer.querySelectorAll('.dragging').forEach(el => el.classList.remove('dragging'));
         });
 
         sectionElement.addEventListener('click', () => {
             currentSection = section;
+            showWarnings([]);
+            sectionTitle.classList.remove('stale');
+            sectionTitle.title = '';
             sectionTitle.value = section.Title;
             sectionContent.value = section.Content;
+            renderPreview();
         });
 
-        sectionsContainer.appendChild(sectionElement);
+        return sectionElement;
     }
 
-    async function updateSectionsOrder() {
-        const sectionElements = document.querySelectorAll('.section-item');
-        const newOrder = Array.from(sectionElements).map(el => el.dataset.id);
+    // IDs dos filhos diretos de parentID, na ordem da lista
+    function childIDs(parentID) {
+        return [...sectionsContainer.querySelectorAll('.section-item')]
+            .filter(el => el.dataset.parent === (parentID || ''))
+            .map(el => el.dataset.id);
+    }
 
-        try {
-            const response = await fetch('/api/sections/reorder', {
-                method: 'POST',
-                headers: {
-                    'Content-Type': 'application/json',
-                },
-                body: JSON.stringify({ order: newOrder }),
-            });
+    // A lista está em pré-ordem: a subárvore de uma seção são os itens
+    // seguintes com profundidade maior que a dela
+    function subtreeElements(id) {
+        const first = sectionsContainer.querySelector(`[data-id="${id}"]`);
+        if (!first) return [];
+        const depth = Number(first.dataset.depth);
+        const elements = [first];
+        for (let el = first.nextElementSibling; el && Number(el.dataset.depth) > depth; el = el.nextElementSibling) {
+            elements.push(el);
+        }
+        return elements;
+    }
 
-            if (!response.ok) throw new Error('Erro ao reordenar seções');
-        } catch (error) {
-            console.error('Erro:', error);
-            alert('Erro ao reordenar seções');
+    // Recuar cada seção conforme a profundidade na árvore
+    function indentSections() {
+        const depths = {};
+        sectionsContainer.querySelectorAll('.section-item').forEach(el => {
+            const parent = el.dataset.parent;
+            const depth = parent && parent in depths ? depths[parent] + 1 : 0;
+            depths[el.dataset.id] = depth;
+            el.dataset.depth = depth;
+            el.style.marginLeft = `${depth * 16}px`;
+        });
+    }
+
+    function addSectionToList(section) {
+        const element = createSectionElement(section);
+        const parent = section.ParentID && subtreeElements(section.ParentID);
+        if (parent && parent.length) {
+            parent[parent.length - 1].after(element);
+        } else {
+            sectionsContainer.appendChild(element);
         }
+        indentSections();
+    }
+
+    function replaceSectionInList(section) {
+        const element = sectionsContainer.querySelector(`[data-id="${section.ID}"]`);
+        if (element) {
+            element.replaceWith(createSectionElement(section));
+            indentSections();
+        } else {
+            addSectionToList(section);
+        }
+    }
+
+    function renderSections(list) {
+        sections = list;
+        sectionsContainer.innerHTML = '';
+        sections.forEach(section => sectionsContainer.appendChild(createSectionElement(section)));
+        indentSections();
+    }
+
+    async function fetchSections() {
+        const response = await fetch(`${api}/sections`);
+        if (!response.ok) throw new Error('Erro ao carregar seções');
+        renderSections(await response.json());
     }
 
     // Carregar seções existentes
     async function loadSections() {
         try {
-            const response = await fetch('/api/sections');
-            if (!response.ok) throw new Error('Erro ao carregar seções');
-            
-            sections = await response.json();
-            sections.forEach(addSectionToList);
+            await fetchSections();
+
+            // abrir a seção indicada na URL, vinda de um resultado de busca
+            const target = window.location.hash.slice(1);
+            const element = target && sectionsContainer.querySelector(`[data-id="${target}"]`);
+            if (element) element.click();
         } catch (error) {
             console.error('Erro:', error);
             alert('Erro ao carregar seções');
         }
     }
 
+    // Busca em todas as seções do workspace
+    let searchTimer = null;
+    searchInput.addEventListener('input', () => {
+        clearTimeout(searchTimer);
+        searchTimer = setTimeout(runSearch, 250);
+    });
+
+    async function runSearch() {
+        const q = searchInput.value.trim();
+        searchResults.innerHTML = '';
+        if (!q) return;
+
+        try {
+            const response = await fetch(`/api/search?q=${encodeURIComponent(q)}`);
+            if (!response.ok) throw new Error('Erro na busca');
+
+            const hits = await response.json();
+            if (hits.length === 0) {
+                searchResults.textContent = 'Nenhum resultado';
+                return;
+            }
+
+            hits.forEach(hit => {
+                const item = document.createElement('div');
+                item.className = 'search-hit';
+                // TitleHTML e Snippet já vêm escapados do servidor, só com <mark>
+                item.innerHTML = `
+                    <div class="search-hit-title">${hit.TitleHTML}</div>
+                    <div class="search-hit-doc"></div>
+                    <div class="search-hit-snippet">${hit.Snippet}</div>
+                `;
+                item.querySelector('.search-hit-doc').textContent = hit.DocumentTitle;
+                item.addEventListener('click', () => {
+                    if (hit.DocumentID !== docID) {
+                        window.location.href = `/?doc=${encodeURIComponent(hit.DocumentID)}#${hit.SectionID}`;
+                        return;
+                    }
+                    const element = sectionsContainer.querySelector(`[data-id="${hit.SectionID}"]`);
+                    if (element) {
+                        element.scrollIntoView({ block: 'nearest' });
+                        element.click();
+                    }
+                });
+                searchResults.appendChild(item);
+            });
+        } catch (error) {
+            console.error('Erro:', error);
+        }
+    }
+
+    // Receber em tempo real as alterações feitas por outros editores
+    function listenForChanges() {
+        const events = new EventSource(`${api}/events`);
+        let reconnecting = false;
+
+        events.addEventListener('open', () => {
+            // ao reconectar, eventos perdidos são recuperados recarregando a lista
+            if (reconnecting) loadSections();
+            reconnecting = false;
+        });
+
+        events.addEventListener('error', () => {
+            reconnecting = true;
+        });
+
+        events.addEventListener('section.created', e => {
+            const { Section: section } = JSON.parse(e.data);
+            if (!sectionsContainer.querySelector(`[data-id="${section.ID}"]`)) {
+                addSectionToList(section);
+            }
+        });
+
+        events.addEventListener('section.updated', e => {
+            const { Section: section } = JSON.parse(e.data);
+            replaceSectionInList(section);
+            if (currentSection && currentSection.ID === section.ID && section.Version > currentSection.Version) {
+                sectionTitle.classList.add('stale');
+                sectionTitle.title = 'Esta seção foi alterada por outra pessoa';
+            }
+        });
+
+        events.addEventListener('section.deleted', e => {
+            const { ID: id } = JSON.parse(e.data);
+            const element = sectionsContainer.querySelector(`[data-id="${id}"]`);
+            if (element) element.remove();
+            if (currentSection && currentSection.ID === id) {
+                alert('A seção em edição foi excluída por outra pessoa');
+                currentSection = null;
+            }
+        });
+
+        events.addEventListener('sections.reordered', () => {
+            // a árvore pode ter mudado junto com a ordem: recarrega tudo
+            if (dragId) return;
+            fetchSections().catch(error => console.error('Erro:', error));
+        });
+    }
+
     loadSections();
+    listenForChanges();
 }); 
\ No newline at end of file
@@ -0,0 +1,281 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+const (
+	sectionsDir  = "sections"      // arquivos .md de cada seção
+	outputDir    = "output"        // documento final combinado
+	manifestFile = "document.json" // metadados e ordem das seções
+)
+
+// frontMatterDelim separa o cabeçalho de metadados do conteúdo da seção
+const frontMatterDelim = "---"
+
+// manifest é o formato persistido do Document. As seções em si ficam
+// em arquivos próprios; aqui guardamos apenas a ordem dos IDs.
+type manifest struct {
+	Title      string   `json:"title"`
+	OutputPath string   `json:"outputPath"`
+	Sections   []string `json:"sections"`
+}
+
+// initDirs cria os diretórios de seções e de saída do documento
+func (d *Document) initDirs() error {
+	for _, dir := range []string{sectionsDir, outputDir} {
+		path := filepath.Join(d.Dir, dir)
+		if err := os.MkdirAll(path, 0755); err != nil {
+			return fmt.Errorf("erro ao criar diretório %s: %v", path, err)
+		}
+	}
+	return nil
+}
+
+// Load reconstrói d.Sections a partir do manifesto e dos arquivos em sections/.
+// Arquivos de seção que não constam no manifesto são anexados ao final,
+// respeitando a ordem gravada no front-matter.
+func (d *Document) Load() error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if err := d.initDirs(); err != nil {
+		return err
+	}
+
+	manifestPath := filepath.Join(d.Dir, manifestFile)
+	data, err := os.ReadFile(manifestPath)
+	var m manifest
+	switch {
+	case err == nil:
+		if err := json.Unmarshal(data, &m); err != nil {
+			return fmt.Errorf("erro ao ler manifesto %s: %v", manifestPath, err)
+		}
+	case errors.Is(err, os.ErrNotExist):
+		// documento novo: apenas as seções soltas em sections/, se houver
+	default:
+		return fmt.Errorf("erro ao ler manifesto %s: %v", manifestPath, err)
+	}
+
+	files, err := filepath.Glob(filepath.Join(d.Dir, sectionsDir, "*.md"))
+	if err != nil {
+		return err
+	}
+
+	byID := make(map[string]TextSection, len(files))
+	for _, file := range files {
+		section, err := readSectionFile(file)
+		if err != nil {
+			return err
+		}
+		byID[section.ID] = section
+	}
+
+	sections := make([]TextSection, 0, len(byID))
+	for _, id := range m.Sections {
+		section, ok := byID[id]
+		if !ok {
+			slog.Warn("seção do manifesto não encontrada", "document", d.ID, "section", id, "dir", sectionsDir)
+			continue
+		}
+		sections = append(sections, section)
+		delete(byID, id)
+	}
+
+	orphans := make([]TextSection, 0, len(byID))
+	for _, section := range byID {
+		orphans = append(orphans, section)
+	}
+	sort.Slice(orphans, func(i, j int) bool {
+		if orphans[i].Order != orphans[j].Order {
+			return orphans[i].Order < orphans[j].Order
+		}
+		return orphans[i].ID < orphans[j].ID
+	})
+	sections = append(sections, orphans...)
+
+	for i := range sections {
+		sections[i].Order = i
+	}
+
+	if m.Title != "" {
+		d.Title = m.Title
+	}
+	if m.OutputPath != "" {
+		d.OutputPath = m.OutputPath
+	}
+	d.Sections = sections
+	d.normalizeTree()
+
+	d.index = newSearchIndex()
+	for i := range d.Sections {
+		d.index.add(&d.Sections[i])
+	}
+	return nil
+}
+
+// saveManifest grava o manifesto com a ordem atual das seções.
+// Deve ser chamado com d.mu travado.
+func (d *Document) saveManifest() error {
+	m := manifest{
+		Title:      d.Title,
+		OutputPath: d.OutputPath,
+		Sections:   make([]string, len(d.Sections)),
+	}
+	for i, s := range d.Sections {
+		m.Sections[i] = s.ID
+	}
+
+	data, err := json.MarshalIndent(m, "", "  ")
+	if err != nil {
+		return err
+	}
+	return writeFileAtomic(filepath.Join(d.Dir, manifestFile), append(data, '\n'), 0644)
+}
+
+// saveSection grava a seção no seu arquivo Markdown com front-matter
+func saveSection(s *TextSection) error {
+	var b strings.Builder
+	b.WriteString(frontMatterDelim + "\n")
+	fmt.Fprintf(&b, "id: %s\n", s.ID)
+	if s.ParentID != "" {
+		fmt.Fprintf(&b, "parent: %s\n", s.ParentID)
+	}
+	fmt.Fprintf(&b, "title: %s\n", strconv.Quote(s.Title))
+	fmt.Fprintf(&b, "order: %d\n", s.Order)
+	fmt.Fprintf(&b, "version: %d\n", s.Version)
+	b.WriteString(frontMatterDelim + "\n")
+	b.WriteString(s.Content)
+
+	if err := writeFileAtomic(s.FilePath, []byte(b.String()), 0644); err != nil {
+		return fmt.Errorf("erro ao salvar seção %s: %v", s.ID, err)
+	}
+	return nil
+}
+
+// readSectionFile lê uma seção gravada por saveSection
+func readSectionFile(path string) (TextSection, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return TextSection{}, fmt.Errorf("erro ao ler seção %s: %v", path, err)
+	}
+
+	section := TextSection{FilePath: path, Version: 1}
+	content := string(data)
+
+	header, body, ok := splitFrontMatter(content)
+	if !ok {
+		return TextSection{}, fmt.Errorf("seção %s sem front-matter", path)
+	}
+
+	scanner := bufio.NewScanner(strings.NewReader(header))
+	for scanner.Scan() {
+		key, value, found := strings.Cut(scanner.Text(), ":")
+		if !found {
+			continue
+		}
+		value = strings.TrimSpace(value)
+
+		switch strings.TrimSpace(key) {
+		case "id":
+			section.ID = value
+		case "parent":
+			section.ParentID = value
+		case "title":
+			if unquoted, err := strconv.Unquote(value); err == nil {
+				value = unquoted
+			}
+			section.Title = value
+		case "order":
+			section.Order, err = strconv.Atoi(value)
+			if err != nil {
+				return TextSection{}, fmt.Errorf("ordem inválida em %s: %v", path, err)
+			}
+		case "version":
+			section.Version, err = strconv.Atoi(value)
+			if err != nil {
+				return TextSection{}, fmt.Errorf("versão inválida em %s: %v", path, err)
+			}
+		}
+	}
+
+	if section.ID == "" {
+		section.ID = strings.TrimSuffix(filepath.Base(path), ".md")
+	}
+	section.Content = body
+	return section, nil
+}
+
+// hasSectionFrontMatter indica se o arquivo começa com o front-matter de
+// uma seção, com ao menos o campo id
+func hasSectionFrontMatter(path string) bool {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return false
+	}
+	header, _, ok := splitFrontMatter(string(data))
+	if !ok {
+		return false
+	}
+	for _, line := range strings.Split(header, "\n") {
+		if key, value, found := strings.Cut(line, ":"); found && strings.TrimSpace(key) == "id" && strings.TrimSpace(value) != "" {
+			return true
+		}
+	}
+	return false
+}
+
+// splitFrontMatter separa o bloco entre os delimitadores "---" do restante
+func splitFrontMatter(content string) (header, body string, ok bool) {
+	rest, found := strings.CutPrefix(content, frontMatterDelim+"\n")
+	if !found {
+		return "", content, false
+	}
+	header, body, found = strings.Cut(rest, "\n"+frontMatterDelim+"\n")
+	if !found {
+		return "", content, false
+	}
+	return header, body, true
+}
+
+// writeFileAtomic grava em um arquivo temporário no mesmo diretório e
+// renomeia por cima do destino, para nunca deixar um arquivo pela metade.
+// O temporário já recebe perm antes da troca, então o destino nunca fica
+// visível com permissões mais abertas.
+func writeFileAtomic(path string, data []byte, perm os.FileMode) error {
+	dir := filepath.Dir(path)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+
+	tmp, err := os.CreateTemp(dir, "."+filepath.Base(path)+".tmp-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	if err := os.Chmod(tmp.Name(), perm); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
@@ -0,0 +1,39 @@
+// Busca no site estático usando search-index.json, gerado na exportação.
+// Os termos são comparados sem acentos, como em /api/search.
+(function() {
+    const input = document.getElementById('search-input');
+    const results = document.getElementById('search-results');
+    let index = null;
+
+    function fold(text) {
+        return text.normalize('NFD').replace(/[\u0300-\u036f]/g, '').toLowerCase();
+    }
+
+    async function load() {
+        if (!index) {
+            const response = await fetch('search-index.json');
+            index = (await response.json()).map(page => ({
+                ...page,
+                folded: fold(page.title + ' ' + page.text)
+            }));
+        }
+        return index;
+    }
+
+    input.addEventListener('input', async () => {
+        const terms = fold(input.value).split(/\s+/).filter(Boolean);
+        results.innerHTML = '';
+        if (terms.length === 0) return;
+
+        const pages = await load();
+        pages.filter(page => terms.every(term => page.folded.includes(term)))
+            .forEach(page => {
+                const li = document.createElement('li');
+                const a = document.createElement('a');
+                a.href = page.url;
+                a.textContent = page.title;
+                li.appendChild(a);
+                results.appendChild(li);
+            });
+    });
+})();
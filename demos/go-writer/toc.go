@@ -0,0 +1,187 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+)
+
+// slugger gera âncoras no mesmo formato do GitHub: minúsculas, sem
+// pontuação, espaços viram "-" e repetições recebem sufixo -1, -2...
+type slugger struct {
+	seen map[string]int
+}
+
+func newSlugger() *slugger {
+	return &slugger{seen: make(map[string]int)}
+}
+
+// slug retorna a âncora para o texto do heading, registrando-a.
+// Segue o algoritmo do github-slugger: "a", "a-1", "a-2"...
+func (s *slugger) slug(text string) string {
+	result := githubAnchor(text)
+	if _, taken := s.seen[result]; taken {
+		base := result
+		for {
+			s.seen[base]++
+			result = fmt.Sprintf("%s-%d", base, s.seen[base])
+			if _, taken := s.seen[result]; !taken {
+				break
+			}
+		}
+	}
+	s.seen[result] = 0
+	return result
+}
+
+// githubAnchor converte o texto de um heading na âncora usada pelo GitHub
+func githubAnchor(text string) string {
+	var b strings.Builder
+	for _, r := range strings.ToLower(strings.TrimSpace(text)) {
+		switch {
+		case unicode.IsLetter(r), unicode.IsDigit(r), unicode.IsMark(r), r == '_', r == '-':
+			b.WriteRune(r)
+		case r == ' ':
+			b.WriteByte('-')
+		}
+	}
+	return b.String()
+}
+
+// markdownHeadings retorna o texto dos headings ATX (# ...) do conteúdo,
+// ignorando linhas dentro de blocos de código cercados por ``` ou ~~~
+func markdownHeadings(content string) []string {
+	var headings []string
+	var fences fenceScanner
+	for _, line := range strings.Split(content, "\n") {
+		if fences.scan(line) != textLine {
+			continue
+		}
+		if _, text, ok := parseHeading(line); ok {
+			headings = append(headings, text)
+		}
+	}
+	return headings
+}
+
+// shiftHeadings desloca os headings do conteúdo de uma seção cujo título
+// tem nível base, para que o mais alto fique logo abaixo dele. Sem isso um
+// "# Título" importado para uma subseção sairia acima da própria seção no
+// documento gerado. Conteúdo que já está abaixo do título não muda.
+func shiftHeadings(content string, base int) string {
+	lines := strings.Split(content, "\n")
+	var headings []int
+	top := 0
+	var fences fenceScanner
+	for i, line := range lines {
+		if fences.scan(line) != textLine {
+			continue
+		}
+		if level, _, ok := parseHeading(line); ok {
+			headings = append(headings, i)
+			if top == 0 || level < top {
+				top = level
+			}
+		}
+	}
+	if top == 0 || top > base {
+		return content
+	}
+
+	shift := base + 1 - top
+	for _, i := range headings {
+		level, _, _ := parseHeading(lines[i])
+		rest := strings.TrimLeft(lines[i], " ")[level:]
+		lines[i] = strings.Repeat("#", min(level+shift, 6)) + rest
+	}
+	return strings.Join(lines, "\n")
+}
+
+// lineKind classifica uma linha em relação aos blocos de código cercados
+type lineKind int
+
+const (
+	textLine  lineKind = iota // fora de blocos de código
+	fenceLine                 // cerca que abre ou fecha um bloco
+	codeLine                  // dentro de um bloco
+)
+
+// codeFence é uma cerca de bloco de código: o caractere (` ou ~) e
+// quantas vezes ele se repete
+type codeFence struct {
+	char  byte
+	width int
+}
+
+// fenceScanner acompanha, linha a linha, os blocos de código cercados
+// pelas regras do CommonMark: o bloco só fecha com uma cerca do mesmo
+// caractere, ao menos do tamanho da abertura e sem info string. Assim um
+// "```go" dentro de um bloco aberto com ```` é código, não fechamento.
+// A indentação é ignorada, para reconhecer blocos dentro de listas.
+type fenceScanner struct {
+	open codeFence // cerca do bloco aberto; valor zero fora de blocos
+}
+
+// scan classifica a próxima linha do texto
+func (s *fenceScanner) scan(line string) lineKind {
+	fence, info, ok := parseFence(line)
+	if s.open.char == 0 {
+		if !ok {
+			return textLine
+		}
+		s.open = fence
+		return fenceLine
+	}
+	if ok && fence.char == s.open.char && fence.width >= s.open.width && info == "" {
+		s.open = codeFence{}
+		return fenceLine
+	}
+	return codeLine
+}
+
+// inCode informa se a última linha lida deixou um bloco aberto
+func (s *fenceScanner) inCode() bool {
+	return s.open.char != 0
+}
+
+// parseFence reconhece uma cerca: três ou mais ` ou ~, seguidos de um
+// info string opcional ("```go"). Com crases, o info string não pode ter
+// crase, ou a linha seria código inline.
+func parseFence(line string) (fence codeFence, info string, ok bool) {
+	line = strings.TrimLeft(line, " \t")
+	if line == "" || (line[0] != '`' && line[0] != '~') {
+		return codeFence{}, "", false
+	}
+	n := 0
+	for n < len(line) && line[n] == line[0] {
+		n++
+	}
+	info = strings.TrimSpace(line[n:])
+	if n < 3 || (line[0] == '`' && strings.Contains(info, "`")) {
+		return codeFence{}, "", false
+	}
+	return codeFence{char: line[0], width: n}, info, true
+}
+
+// parseHeading reconhece um heading ATX e retorna seu nível e texto
+func parseHeading(line string) (level int, text string, ok bool) {
+	line = strings.TrimLeft(line, " ")
+	for level < len(line) && line[level] == '#' {
+		level++
+	}
+	if level == 0 || level > 6 {
+		return 0, "", false
+	}
+
+	rest := line[level:]
+	if rest != "" && rest[0] != ' ' && rest[0] != '\t' {
+		return 0, "", false
+	}
+
+	text = strings.TrimSpace(rest)
+	// sequência de fechamento opcional: "## Título ##", mas não "## C#"
+	if closed := strings.TrimRight(text, "#"); closed == "" || strings.HasSuffix(closed, " ") {
+		text = strings.TrimSpace(closed)
+	}
+	return level, text, true
+}
@@ -0,0 +1,354 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+)
+
+// trashDir guarda as seções excluídas do documento: trash/<id>.json
+const trashDir = "trash"
+
+// trashPurgeInterval é de quanto em quanto tempo a lixeira é esvaziada
+const trashPurgeInterval = time.Hour
+
+// ErrTrashItemNotFound é retornado quando a seção não está na lixeira
+var ErrTrashItemNotFound = errors.New("seção não encontrada na lixeira")
+
+// TrashItem é uma seção excluída, com o que é preciso para devolvê-la ao
+// mesmo lugar: o pai, o irmão anterior, a posição entre os irmãos e as
+// subseções que subiram de nível com a exclusão
+type TrashItem struct {
+	Section       TextSection
+	DocumentID    string
+	DocumentTitle string `json:",omitempty"` // só na listagem
+	DeletedAt     time.Time
+	DeletedBy     string
+	ParentID      string
+	After         string // irmão anterior; vazio se era o primeiro
+	Index         int
+	Children      []string
+}
+
+// trashFile retorna o caminho do item da seção na lixeira
+func (d *Document) trashFile(sectionID string) string {
+	return filepath.Join(d.Dir, trashDir, sectionID+".json")
+}
+
+// moveToTrash grava a seção i na lixeira antes de ela sair do documento.
+// Deve ser chamado com d.mu travado para escrita.
+func (d *Document) moveToTrash(i int, author string) error {
+	s := d.Sections[i]
+	children := d.children()
+	siblings := children[s.ParentID]
+	index := slices.Index(siblings, s.ID)
+
+	item := TrashItem{
+		Section:    s,
+		DocumentID: d.ID,
+		DeletedAt:  time.Now().UTC(),
+		DeletedBy:  author,
+		ParentID:   s.ParentID,
+		Index:      index,
+		Children:   children[s.ID],
+	}
+	if item.DeletedBy == "" {
+		item.DeletedBy = unknownAuthor
+	}
+	if index > 0 {
+		item.After = siblings[index-1]
+	}
+
+	data, err := json.MarshalIndent(item, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := writeFileAtomic(d.trashFile(s.ID), append(data, '\n'), 0644); err != nil {
+		return fmt.Errorf("erro ao mover seção %s para a lixeira: %v", s.ID, err)
+	}
+	return nil
+}
+
+// readTrashItem lê um item da lixeira. Deve ser chamado com d.mu travado.
+// O ID vem da URL: é validado antes de virar caminho, e o arquivo só vale
+// como item se for mesmo da seção pedida.
+func (d *Document) readTrashItem(sectionID string) (*TrashItem, error) {
+	if !validSectionID(sectionID) {
+		return nil, fmt.Errorf("%w: %q", ErrTrashItemNotFound, sectionID)
+	}
+	data, err := os.ReadFile(d.trashFile(sectionID))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, fmt.Errorf("%w: %s", ErrTrashItemNotFound, sectionID)
+	}
+	if err != nil {
+		return nil, err
+	}
+	var item TrashItem
+	if err := json.Unmarshal(data, &item); err != nil {
+		return nil, fmt.Errorf("item corrompido na lixeira %s: %v", sectionID, err)
+	}
+	if item.Section.ID != sectionID {
+		return nil, fmt.Errorf("item corrompido na lixeira %s: contém a seção %q", sectionID, item.Section.ID)
+	}
+	return &item, nil
+}
+
+// Trash lista as seções excluídas do documento, das mais recentes para
+// as mais antigas. Arquivos da lixeira ilegíveis são ignorados.
+func (d *Document) Trash() ([]TrashItem, error) {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+	return d.trash()
+}
+
+func (d *Document) trash() ([]TrashItem, error) {
+	files, err := filepath.Glob(filepath.Join(d.Dir, trashDir, "*.json"))
+	if err != nil {
+		return nil, err
+	}
+
+	items := make([]TrashItem, 0, len(files))
+	for _, file := range files {
+		item, err := d.readTrashItem(strings.TrimSuffix(filepath.Base(file), ".json"))
+		if err != nil {
+			// um arquivo estragado ou alheio não pode esconder o resto da
+			// lixeira nem travar o esvaziamento; ele fica onde está
+			slog.Warn("item da lixeira ignorado", "document", d.ID, "path", file, "err", err)
+			continue
+		}
+		item.DocumentTitle = d.Title
+		items = append(items, *item)
+	}
+	sort.Slice(items, func(i, j int) bool {
+		return items[i].DeletedAt.After(items[j].DeletedAt)
+	})
+	return items, nil
+}
+
+// RestoreSection devolve a seção da lixeira ao documento. Ela volta logo
+// depois do irmão anterior, ou na mesma posição se ele não existir mais;
+// se o pai foi excluído, volta na raiz. As subseções que ainda estão onde
+// a exclusão as deixou voltam a ser filhas dela.
+func (d *Document) RestoreSection(id string) (*TextSection, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	item, err := d.readTrashItem(id)
+	if err != nil {
+		return nil, err
+	}
+	if d.indexOf(id) >= 0 {
+		return nil, fmt.Errorf("%w: a seção %s já existe no documento", ErrInvalidSection, id)
+	}
+
+	parent := item.ParentID
+	if parent != "" && d.indexOf(parent) < 0 {
+		parent = ""
+	}
+
+	children := d.children()
+	siblings := children[parent]
+	pos := min(max(item.Index, 0), len(siblings))
+	if j := slices.Index(siblings, item.After); item.After != "" && j >= 0 {
+		pos = j + 1
+	}
+	children[parent] = slices.Insert(siblings, pos, id)
+
+	moved := []string{id}
+	for _, c := range item.Children {
+		ci := d.indexOf(c)
+		if ci < 0 || d.Sections[ci].ParentID != parent {
+			continue
+		}
+		children[parent] = slices.DeleteFunc(children[parent], func(s string) bool { return s == c })
+		children[id] = append(children[id], c)
+		d.Sections[ci].ParentID = id
+		moved = append(moved, c)
+	}
+
+	section := item.Section
+	section.ParentID = parent
+	section.FilePath = filepath.Join(d.Dir, sectionsDir, id+".md")
+	d.Sections = append(d.Sections, section)
+
+	if err := d.applyOrder(preorder(children), moved...); err != nil {
+		return nil, err
+	}
+	if err := d.saveManifest(); err != nil {
+		return nil, err
+	}
+	if err := os.Remove(d.trashFile(id)); err != nil {
+		return nil, fmt.Errorf("erro ao remover %s da lixeira: %v", id, err)
+	}
+
+	restored := d.Sections[d.indexOf(id)]
+	d.searchIndex().add(&restored)
+
+	published := restored
+	d.publish(Event{Type: EventSectionCreated, Section: &published})
+	d.publish(Event{Type: EventSectionsReordered, Order: d.sectionIDs()})
+	return &restored, nil
+}
+
+// PurgeTrashItem exclui definitivamente a seção da lixeira, junto com o
+// histórico de revisões
+func (d *Document) PurgeTrashItem(id string) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if _, err := d.readTrashItem(id); err != nil {
+		return err
+	}
+	return d.purgeTrashItem(id)
+}
+
+func (d *Document) purgeTrashItem(id string) error {
+	if !validSectionID(id) {
+		return fmt.Errorf("%w: %q", ErrTrashItemNotFound, id)
+	}
+	if err := os.Remove(d.trashFile(id)); err != nil {
+		return err
+	}
+	if err := os.Remove(d.revisionsFile(id)); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+	return nil
+}
+
+// PurgeTrash exclui definitivamente os itens excluídos antes de cutoff e
+// retorna quantos foram removidos
+func (d *Document) PurgeTrash(cutoff time.Time) (int, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	items, err := d.trash()
+	if err != nil {
+		return 0, err
+	}
+	purged := 0
+	for _, item := range items {
+		if !item.DeletedAt.Before(cutoff) {
+			continue
+		}
+		if err := d.purgeTrashItem(item.Section.ID); err != nil {
+			return purged, err
+		}
+		purged++
+	}
+	return purged, nil
+}
+
+// Trash lista a lixeira de todos os documentos do workspace
+func (ws *Workspace) Trash() ([]TrashItem, error) {
+	items := []TrashItem{}
+	for _, doc := range ws.List() {
+		docItems, err := doc.Trash()
+		if err != nil {
+			return nil, err
+		}
+		items = append(items, docItems...)
+	}
+	sort.SliceStable(items, func(i, j int) bool {
+		return items[i].DeletedAt.After(items[j].DeletedAt)
+	})
+	return items, nil
+}
+
+// purgeTrashLoop esvazia a lixeira dos itens mais antigos que retention
+// na partida e depois a cada trashPurgeInterval, até ctx terminar
+func (ws *Workspace) purgeTrashLoop(ctx context.Context, retention time.Duration, logger *slog.Logger) {
+	ticker := time.NewTicker(trashPurgeInterval)
+	defer ticker.Stop()
+
+	for {
+		cutoff := time.Now().Add(-retention)
+		for _, doc := range ws.List() {
+			purged, err := doc.PurgeTrash(cutoff)
+			if err != nil {
+				logger.Error("erro ao esvaziar lixeira", "document", doc.ID, "err", err)
+			}
+			if purged > 0 {
+				logger.Info("lixeira esvaziada", "document", doc.ID, "sections", purged, "retention", retention)
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// handleTrash lista em GET /api/trash as seções excluídas de todos os
+// documentos, ou só do documento em ?doc=
+func handleTrash(ws *Workspace) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			w.Header().Set("Allow", "GET")
+			http.Error(w, "método não permitido", http.StatusMethodNotAllowed)
+			return
+		}
+
+		var items []TrashItem
+		var err error
+		if id := r.URL.Query().Get("doc"); id != "" {
+			doc, getErr := ws.Get(id)
+			if getErr != nil {
+				writeSectionError(w, getErr)
+				return
+			}
+			items, err = doc.Trash()
+		} else {
+			items, err = ws.Trash()
+		}
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		writeJSON(w, http.StatusOK, items)
+	}
+}
+
+// handleTrashItem exclui definitivamente uma seção da lixeira
+func handleTrashItem(doc *Document) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodDelete {
+			w.Header().Set("Allow", "DELETE")
+			http.Error(w, "método não permitido", http.StatusMethodNotAllowed)
+			return
+		}
+		if err := doc.PurgeTrashItem(r.PathValue("id")); err != nil {
+			writeSectionError(w, err)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+// handleTrashRestore devolve uma seção da lixeira ao documento
+func handleTrashRestore(doc *Document) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			w.Header().Set("Allow", "POST")
+			http.Error(w, "método não permitido", http.StatusMethodNotAllowed)
+			return
+		}
+		section, err := doc.RestoreSection(r.PathValue("id"))
+		if err != nil {
+			writeSectionError(w, err)
+			return
+		}
+		w.Header().Set("ETag", sectionETag(section))
+		writeJSON(w, http.StatusOK, section)
+	}
+}
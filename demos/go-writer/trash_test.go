@@ -0,0 +1,92 @@
+package main
+
+import (
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestTrashRoutesRejectPathTraversal(t *testing.T) {
+	ts := newTestServer(t)
+	editor := ts.login(t, "editor")
+	// qualquer objeto JSON passaria por um TrashItem
+	writeFiles(t, ts.ws.Root, map[string]string{"config.json": "{}\n"})
+
+	// trash/<id>.json com id = ../../../users cai em <raiz>/users.json
+	for _, name := range []string{"users", "config"} {
+		id := "..%2F..%2F..%2F" + name
+		editor.expect(http.StatusNotFound, http.MethodDelete, ts.docPath("trash", id), nil)
+		editor.expect(http.StatusNotFound, http.MethodPost, ts.docPath("trash", id, "restore"), nil)
+
+		if _, err := os.Stat(filepath.Join(ts.ws.Root, name+".json")); err != nil {
+			t.Fatalf("%s.json removido pela lixeira: %v", name, err)
+		}
+	}
+}
+
+func TestTrashRoundTrip(t *testing.T) {
+	ts := newTestServer(t)
+	editor := ts.login(t, "editor")
+	section := editor.addSection("Intro", "v1")
+	path := ts.docPath("sections", section.ID)
+
+	editor.expect(http.StatusNoContent, http.MethodDelete, path, nil, "If-Match", `"1"`)
+	editor.expect(http.StatusNotFound, http.MethodGet, path, nil)
+
+	var items []TrashItem
+	decode(t, editor.expect(http.StatusOK, http.MethodGet, "/api/trash?doc="+ts.doc.ID, nil), &items)
+	if len(items) != 1 || items[0].Section.ID != section.ID || items[0].DeletedBy != "editor" {
+		t.Fatalf("lixeira = %+v", items)
+	}
+
+	editor.expect(http.StatusOK, http.MethodPost, ts.docPath("trash", section.ID, "restore"), nil)
+	var restored TextSection
+	decode(t, editor.expect(http.StatusOK, http.MethodGet, path, nil), &restored)
+	if restored.Content != "v1" {
+		t.Errorf("seção restaurada = %+v", restored)
+	}
+
+	// excluída de novo e apagada de vez, leva junto o histórico
+	editor.expect(http.StatusNoContent, http.MethodDelete, path, nil, "If-Match", `"1"`)
+	editor.expect(http.StatusNoContent, http.MethodDelete, ts.docPath("trash", section.ID), nil)
+	editor.expect(http.StatusNotFound, http.MethodPost, ts.docPath("trash", section.ID, "restore"), nil)
+	if _, err := os.Stat(ts.doc.revisionsFile(section.ID)); !os.IsNotExist(err) {
+		t.Errorf("histórico continua depois de apagar da lixeira: %v", err)
+	}
+}
+
+// Um arquivo estragado ou alheio na lixeira não derruba a listagem nem o
+// esvaziamento dos demais itens
+func TestTrashSkipsBadItems(t *testing.T) {
+	ts := newTestServer(t)
+	editor := ts.login(t, "editor")
+	section := editor.addSection("Intro", "v1")
+	editor.expect(http.StatusNoContent, http.MethodDelete, ts.docPath("sections", section.ID), nil, "If-Match", `"1"`)
+
+	bad := map[string]string{
+		"corrompido.json": "não é JSON",
+		"alheio.json":     `{"Section": {"ID": "outra"}}`,
+	}
+	writeFiles(t, filepath.Join(ts.doc.Dir, trashDir), bad)
+
+	var items []TrashItem
+	decode(t, editor.expect(http.StatusOK, http.MethodGet, "/api/trash", nil), &items)
+	if len(items) != 1 || items[0].Section.ID != section.ID {
+		t.Fatalf("lixeira = %+v", items)
+	}
+
+	purged, err := ts.doc.PurgeTrash(time.Now().Add(time.Hour))
+	if err != nil || purged != 1 {
+		t.Fatalf("PurgeTrash = %d, %v", purged, err)
+	}
+	if _, err := os.Stat(ts.doc.trashFile(section.ID)); !os.IsNotExist(err) {
+		t.Errorf("item válido continua na lixeira: %v", err)
+	}
+	for name := range bad {
+		if _, err := os.Stat(filepath.Join(ts.doc.Dir, trashDir, name)); err != nil {
+			t.Errorf("%s removido: %v", name, err)
+		}
+	}
+}
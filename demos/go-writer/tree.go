@@ -0,0 +1,166 @@
+package main
+
+import (
+	"fmt"
+	"log/slog"
+	"slices"
+)
+
+// As seções formam uma árvore pelo ParentID (vazio na raiz). d.Sections
+// é sempre mantido em pré-ordem: cada seção vem logo depois do pai e dos
+// irmãos anteriores com todas as suas subseções, e Order é a posição
+// nessa lista. Assim quem só lê a ordem (Markdown, exportações) percorre
+// a árvore sem precisar conhecê-la.
+
+// children agrupa os IDs por pai, na ordem atual de d.Sections
+func (d *Document) children() map[string][]string {
+	children := make(map[string][]string)
+	for _, s := range d.Sections {
+		children[s.ParentID] = append(children[s.ParentID], s.ID)
+	}
+	return children
+}
+
+// preorder percorre a árvore em profundidade a partir das raízes
+func preorder(children map[string][]string) []string {
+	var ids []string
+	var walk func(parent string)
+	walk = func(parent string) {
+		for _, id := range children[parent] {
+			ids = append(ids, id)
+			walk(id)
+		}
+	}
+	walk("")
+	return ids
+}
+
+// depths retorna a profundidade de cada seção; as raízes têm 0
+func (d *Document) depths() map[string]int {
+	depths := make(map[string]int, len(d.Sections))
+	for _, s := range d.Sections {
+		// em pré-ordem o pai sempre já foi visto
+		if s.ParentID != "" {
+			depths[s.ID] = depths[s.ParentID] + 1
+		} else {
+			depths[s.ID] = 0
+		}
+	}
+	return depths
+}
+
+// isDescendant informa se id está na subárvore de ancestor
+func (d *Document) isDescendant(id, ancestor string) bool {
+	for id != "" {
+		if id == ancestor {
+			return true
+		}
+		i := d.indexOf(id)
+		if i < 0 {
+			return false
+		}
+		id = d.Sections[i].ParentID
+	}
+	return false
+}
+
+// applyOrder reorganiza d.Sections na ordem dos IDs e regrava as seções
+// cuja ordem ou pai mudou. moved são seções cujo pai mudou.
+func (d *Document) applyOrder(ids []string, moved ...string) error {
+	reordered := make([]TextSection, 0, len(ids))
+	for _, id := range ids {
+		reordered = append(reordered, d.Sections[d.indexOf(id)])
+	}
+	d.Sections = reordered
+
+	for _, id := range moved {
+		i := d.indexOf(id)
+		d.Sections[i].Order = i
+		if err := saveSection(&d.Sections[i]); err != nil {
+			return err
+		}
+	}
+	return d.renumber()
+}
+
+// normalizeTree corrige árvores gravadas à mão ou por versões antigas:
+// pais inexistentes e ciclos viram raiz, e d.Sections volta à pré-ordem.
+// Deve ser chamado com d.mu travado para escrita.
+func (d *Document) normalizeTree() {
+	for i := range d.Sections {
+		s := &d.Sections[i]
+		if s.ParentID == "" {
+			continue
+		}
+		seen := map[string]bool{s.ID: true}
+		for p := s.ParentID; p != ""; {
+			j := d.indexOf(p)
+			if j < 0 || seen[p] {
+				slog.Warn("seção com pai inválido movida para a raiz", "document", d.ID, "section", s.ID, "parent", s.ParentID)
+				s.ParentID = ""
+				break
+			}
+			seen[p] = true
+			p = d.Sections[j].ParentID
+		}
+	}
+
+	ids := preorder(d.children())
+	reordered := make([]TextSection, 0, len(ids))
+	for _, id := range ids {
+		reordered = append(reordered, d.Sections[d.indexOf(id)])
+	}
+	for i := range reordered {
+		reordered[i].Order = i
+	}
+	d.Sections = reordered
+}
+
+// MoveSection move a seção, com todas as suas subseções, para a posição
+// index entre os filhos de parentID ("" para a raiz). Um index além do
+// último filho coloca a seção no fim.
+func (d *Document) MoveSection(id, parentID string, index int) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	i := d.indexOf(id)
+	if i < 0 {
+		return fmt.Errorf("%w: %s", ErrSectionNotFound, id)
+	}
+	if parentID != "" && d.indexOf(parentID) < 0 {
+		return fmt.Errorf("%w: %s", ErrSectionNotFound, parentID)
+	}
+	if d.isDescendant(parentID, id) {
+		return fmt.Errorf("%w: uma seção não pode ser movida para dentro de si mesma", ErrInvalidSection)
+	}
+	if index < 0 {
+		return fmt.Errorf("%w: posição negativa", ErrInvalidSection)
+	}
+
+	children := d.children()
+	old := d.Sections[i].ParentID
+	children[old] = slices.DeleteFunc(children[old], func(c string) bool { return c == id })
+	siblings := children[parentID]
+	index = min(index, len(siblings))
+	children[parentID] = slices.Insert(siblings, index, id)
+
+	d.Sections[i].ParentID = parentID
+	if err := d.applyOrder(preorder(children), id); err != nil {
+		return err
+	}
+	if err := d.saveManifest(); err != nil {
+		return err
+	}
+
+	d.publish(Event{Type: EventSectionsReordered, Order: d.sectionIDs()})
+	return nil
+}
+
+// sectionIDs retorna os IDs na ordem do documento
+func (d *Document) sectionIDs() []string {
+	ids := make([]string, len(d.Sections))
+	for i, s := range d.Sections {
+		ids[i] = s.ID
+	}
+	return ids
+}
@@ -0,0 +1,287 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+
+	"golang.org/x/crypto/bcrypt"
+	"golang.org/x/term"
+)
+
+// usersFile guarda as contas locais na raiz do workspace
+const usersFile = "users.json"
+
+// minPasswordLength é o tamanho mínimo aceito para senhas
+const minPasswordLength = 8
+
+// Role é o papel de um usuário. Cada papel inclui as permissões do
+// anterior: reader só lê, author escreve seções e gera o documento,
+// editor também reordena, exclui e restaura da lixeira.
+type Role string
+
+const (
+	RoleReader Role = "reader"
+	RoleAuthor Role = "author"
+	RoleEditor Role = "editor"
+)
+
+// roleLevel ordena os papéis do menos para o mais privilegiado
+var roleLevel = map[Role]int{
+	RoleReader: 1,
+	RoleAuthor: 2,
+	RoleEditor: 3,
+}
+
+// Allows informa se o papel tem ao menos as permissões de min
+func (r Role) Allows(min Role) bool {
+	return roleLevel[r] >= roleLevel[min]
+}
+
+// parseRole valida o nome de um papel
+func parseRole(s string) (Role, error) {
+	role := Role(strings.ToLower(strings.TrimSpace(s)))
+	if _, ok := roleLevel[role]; !ok {
+		return "", fmt.Errorf("papel inválido %q: use reader, author ou editor", s)
+	}
+	return role, nil
+}
+
+// Erros retornados pelo cadastro de usuários
+var (
+	ErrUserExists         = errors.New("usuário já existe")
+	ErrUserNotFound       = errors.New("usuário não encontrado")
+	ErrInvalidCredentials = errors.New("usuário ou senha inválidos")
+)
+
+// User é uma conta local. A senha é guardada apenas como hash bcrypt.
+type User struct {
+	Username     string `json:"username"`
+	PasswordHash string `json:"passwordHash"`
+	Role         Role   `json:"role"`
+}
+
+// UserStore mantém as contas do workspace em users.json
+type UserStore struct {
+	path string
+
+	mu    sync.RWMutex
+	users map[string]User
+}
+
+// LoadUsers lê root/users.json. Um arquivo ausente equivale a nenhum
+// usuário cadastrado.
+func LoadUsers(root string) (*UserStore, error) {
+	s := &UserStore{
+		path:  filepath.Join(root, usersFile),
+		users: make(map[string]User),
+	}
+
+	data, err := os.ReadFile(s.path)
+	if errors.Is(err, os.ErrNotExist) {
+		return s, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("erro ao ler %s: %v", s.path, err)
+	}
+
+	var users []User
+	if err := json.Unmarshal(data, &users); err != nil {
+		return nil, fmt.Errorf("erro ao ler %s: %v", s.path, err)
+	}
+	for _, u := range users {
+		s.users[u.Username] = u
+	}
+	return s, nil
+}
+
+// save grava todas as contas; o chamador deve manter s.mu travado
+func (s *UserStore) save() error {
+	users := make([]User, 0, len(s.users))
+	for _, u := range s.users {
+		users = append(users, u)
+	}
+	sort.Slice(users, func(i, j int) bool {
+		return users[i].Username < users[j].Username
+	})
+
+	data, err := json.MarshalIndent(users, "", "  ")
+	if err != nil {
+		return err
+	}
+	// o arquivo tem hashes de senha: só o dono lê
+	if err := writeFileAtomic(s.path, data, 0600); err != nil {
+		return fmt.Errorf("erro ao salvar %s: %v", s.path, err)
+	}
+	return nil
+}
+
+// Add cadastra um novo usuário
+func (s *UserStore) Add(username, password string, role Role) error {
+	username = strings.TrimSpace(username)
+	if username == "" {
+		return errors.New("nome de usuário vazio")
+	}
+	if len(password) < minPasswordLength {
+		return fmt.Errorf("a senha deve ter ao menos %d caracteres", minPasswordLength)
+	}
+
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	if err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.users[username]; ok {
+		return fmt.Errorf("%w: %s", ErrUserExists, username)
+	}
+	s.users[username] = User{Username: username, PasswordHash: string(hash), Role: role}
+	if err := s.save(); err != nil {
+		delete(s.users, username)
+		return err
+	}
+	return nil
+}
+
+// SetRole altera o papel de um usuário existente
+func (s *UserStore) SetRole(username string, role Role) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	u, ok := s.users[username]
+	if !ok {
+		return fmt.Errorf("%w: %s", ErrUserNotFound, username)
+	}
+	old := u.Role
+	u.Role = role
+	s.users[username] = u
+	if err := s.save(); err != nil {
+		u.Role = old
+		s.users[username] = u
+		return err
+	}
+	return nil
+}
+
+// Get retorna o usuário, para conferir o papel atual a cada requisição
+func (s *UserStore) Get(username string) (User, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	u, ok := s.users[username]
+	return u, ok
+}
+
+// List retorna os usuários em ordem alfabética
+func (s *UserStore) List() []User {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	users := make([]User, 0, len(s.users))
+	for _, u := range s.users {
+		users = append(users, u)
+	}
+	sort.Slice(users, func(i, j int) bool {
+		return users[i].Username < users[j].Username
+	})
+	return users
+}
+
+// Len retorna quantos usuários estão cadastrados
+func (s *UserStore) Len() int {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return len(s.users)
+}
+
+// dummyHash é comparado quando o usuário não existe, para que a resposta
+// leve o mesmo tempo e não revele quais nomes estão cadastrados
+var dummyHash, _ = bcrypt.GenerateFromPassword([]byte("go-writer"), bcrypt.DefaultCost)
+
+// Authenticate confere usuário e senha
+func (s *UserStore) Authenticate(username, password string) (User, error) {
+	u, ok := s.Get(username)
+	hash := dummyHash
+	if ok {
+		hash = []byte(u.PasswordHash)
+	}
+	if err := bcrypt.CompareHashAndPassword(hash, []byte(password)); err != nil || !ok {
+		return User{}, ErrInvalidCredentials
+	}
+	return u, nil
+}
+
+// readPassword pede a senha na entrada padrão. Num terminal a digitação
+// não aparece na tela; fora dele (em um pipe) lê a primeira linha.
+func readPassword() (string, error) {
+	fmt.Fprint(os.Stderr, "Senha: ")
+	if fd := int(os.Stdin.Fd()); term.IsTerminal(fd) {
+		password, err := term.ReadPassword(fd)
+		fmt.Fprintln(os.Stderr)
+		return string(password), err
+	}
+	password, err := bufio.NewReader(os.Stdin).ReadString('\n')
+	if err != nil && password == "" {
+		return "", err
+	}
+	return strings.TrimRight(password, "\r\n"), nil
+}
+
+// runUser implementa o comando "go-writer user":
+//
+//	go-writer user add <nome> <papel>   (senha lida da entrada padrão)
+//	go-writer user role <nome> <papel>
+//	go-writer user list
+func runUser(root string, args []string) error {
+	const usage = "uso: go-writer user add <nome> <papel> | role <nome> <papel> | list"
+
+	users, err := LoadUsers(root)
+	if err != nil {
+		return err
+	}
+	if len(args) < 1 {
+		return errors.New(usage)
+	}
+
+	switch {
+	case args[0] == "list":
+		for _, u := range users.List() {
+			fmt.Printf("%s\t%s\n", u.Username, u.Role)
+		}
+		return nil
+
+	case args[0] == "add" && len(args) == 3:
+		role, err := parseRole(args[2])
+		if err != nil {
+			return err
+		}
+		password, err := readPassword()
+		if err != nil {
+			return fmt.Errorf("erro ao ler senha: %v", err)
+		}
+		if err := users.Add(args[1], password, role); err != nil {
+			return err
+		}
+		fmt.Printf("Usuário %s criado com papel %s\n", args[1], role)
+		return nil
+
+	case args[0] == "role" && len(args) == 3:
+		role, err := parseRole(args[2])
+		if err != nil {
+			return err
+		}
+		if err := users.SetRole(args[1], role); err != nil {
+			return err
+		}
+		fmt.Printf("Usuário %s agora tem papel %s\n", args[1], role)
+		return nil
+	}
+	return errors.New(usage)
+}
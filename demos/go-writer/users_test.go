@@ -0,0 +1,60 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestUsersFileIsPrivate(t *testing.T) {
+	root := t.TempDir()
+	path := filepath.Join(root, usersFile)
+	// arquivo de uma versão anterior, legível por todos
+	if err := os.WriteFile(path, []byte("[]"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	users, err := LoadUsers(root)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := users.Add("ana", "senha-comprida", RoleEditor); err != nil {
+		t.Fatal(err)
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if perm := info.Mode().Perm(); perm != 0600 {
+		t.Errorf("permissões de %s: %v, esperado 0600", usersFile, perm)
+	}
+	if temps, _ := filepath.Glob(filepath.Join(root, ".*.tmp-*")); len(temps) > 0 {
+		t.Errorf("temporários deixados para trás: %v", temps)
+	}
+}
+
+func TestReadPasswordFromPipe(t *testing.T) {
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	devNull, err := os.OpenFile(os.DevNull, os.O_WRONLY, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer devNull.Close()
+	stdin, stderr := os.Stdin, os.Stderr
+	os.Stdin, os.Stderr = r, devNull // sem o "Senha: " na saída do teste
+	defer func() { os.Stdin, os.Stderr = stdin, stderr }()
+
+	w.WriteString("senha secreta\r\noutra linha\n")
+	w.Close()
+
+	password, err := readPassword()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if password != "senha secreta" {
+		t.Errorf("senha = %q", password)
+	}
+}
@@ -0,0 +1,117 @@
+package main
+
+import (
+	"cmp"
+	"embed"
+	"fmt"
+	"html/template"
+	"io/fs"
+	"net/http"
+	"os"
+)
+
+// embedded traz a interface dentro do binário, para que o servidor não
+// dependa do diretório de trabalho
+//
+//go:embed templates static
+var embedded embed.FS
+
+// pageTemplates são os templates HTML usados pelo servidor e pelas
+// exportações, relativos ao diretório de templates
+var pageTemplates = []string{"index.html", "login.html", "site/page.html"}
+
+// webFiles são os templates e os arquivos estáticos da interface. Fora do
+// modo de desenvolvimento os templates são interpretados uma única vez,
+// na criação; com dev, tudo é relido do disco a cada uso.
+type webFiles struct {
+	templates fs.FS
+	static    fs.FS
+	dev       bool
+	pages     map[string]*template.Template
+}
+
+// web é a interface em uso: a embutida, até que main aplique as opções
+// -dev, -templates e -static
+var web = mustWebFiles(loadWebFiles(config{}))
+
+// loadWebFiles monta a interface a partir das opções. Diretórios vazios
+// usam a cópia embutida; em dev o padrão é templates/ e static/ no
+// diretório atual, como no repositório.
+func loadWebFiles(cfg config) (*webFiles, error) {
+	templatesDir, staticDir := cfg.Templates, cfg.Static
+	if cfg.Dev {
+		templatesDir = cmp.Or(templatesDir, "templates")
+		staticDir = cmp.Or(staticDir, "static")
+	}
+
+	templates, err := webDir(templatesDir, "templates")
+	if err != nil {
+		return nil, err
+	}
+	static, err := webDir(staticDir, "static")
+	if err != nil {
+		return nil, err
+	}
+	return newWebFiles(templates, static, cfg.Dev)
+}
+
+// webDir abre dir no disco ou, se vazio, o diretório embutido equivalente
+func webDir(dir, embeddedDir string) (fs.FS, error) {
+	if dir == "" {
+		return fs.Sub(embedded, embeddedDir)
+	}
+	if _, err := os.Stat(dir); err != nil {
+		return nil, fmt.Errorf("diretório da interface inválido: %v", err)
+	}
+	return os.DirFS(dir), nil
+}
+
+// newWebFiles interpreta os templates de antemão, exceto em modo dev,
+// para que um template quebrado impeça o servidor de subir
+func newWebFiles(templates, static fs.FS, dev bool) (*webFiles, error) {
+	w := &webFiles{templates: templates, static: static, dev: dev}
+	if dev {
+		return w, nil
+	}
+
+	w.pages = make(map[string]*template.Template, len(pageTemplates))
+	for _, name := range pageTemplates {
+		tmpl, err := template.ParseFS(templates, name)
+		if err != nil {
+			return nil, fmt.Errorf("erro ao ler template %s: %v", name, err)
+		}
+		w.pages[name] = tmpl
+	}
+	return w, nil
+}
+
+// mustWebFiles aborta se os arquivos embutidos forem inválidos, o que só
+// acontece com um binário compilado a partir de templates quebrados
+func mustWebFiles(w *webFiles, err error) *webFiles {
+	if err != nil {
+		panic(err)
+	}
+	return w
+}
+
+// page retorna o template name, relido do disco em modo dev
+func (w *webFiles) page(name string) (*template.Template, error) {
+	if w.dev {
+		return template.ParseFS(w.templates, name)
+	}
+	tmpl, ok := w.pages[name]
+	if !ok {
+		return nil, fmt.Errorf("template desconhecido: %s", name)
+	}
+	return tmpl, nil
+}
+
+// readFile lê um arquivo auxiliar do diretório de templates
+func (w *webFiles) readFile(name string) ([]byte, error) {
+	return fs.ReadFile(w.templates, name)
+}
+
+// staticHandler serve os arquivos estáticos da interface
+func (w *webFiles) staticHandler() http.Handler {
+	return http.FileServerFS(w.static)
+}
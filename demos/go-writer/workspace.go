@@ -0,0 +1,253 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// documentsDir guarda um subdiretório por documento: documents/<id>/
+const documentsDir = "documents"
+
+// defaultTitle é usado quando um documento ainda não tem título salvo
+const defaultTitle = "Novo Documento"
+
+// ErrDocumentNotFound é retornado quando o ID não pertence ao workspace
+var ErrDocumentNotFound = errors.New("documento não encontrado")
+
+// Workspace é o registro dos documentos editados pelo servidor. Cada
+// documento tem seu próprio diretório com seções, manifesto e saída.
+type Workspace struct {
+	Root string
+	Lint *LintConfig
+
+	mu   sync.RWMutex
+	docs map[string]*Document
+}
+
+// NewWorkspace cria um workspace vazio com raiz em root
+func NewWorkspace(root string) *Workspace {
+	return &Workspace{
+		Root: root,
+		docs: make(map[string]*Document),
+	}
+}
+
+// Load carrega todos os documentos encontrados em <root>/documents
+func (ws *Workspace) Load() error {
+	entries, err := os.ReadDir(filepath.Join(ws.Root, documentsDir))
+	if err != nil {
+		return fmt.Errorf("erro ao listar documentos: %v", err)
+	}
+
+	ws.mu.Lock()
+	defer ws.mu.Unlock()
+
+	for _, entry := range entries {
+		if !entry.IsDir() {
+			continue
+		}
+		doc := ws.newDocument(entry.Name(), defaultTitle)
+		if err := doc.Load(); err != nil {
+			return err
+		}
+		ws.docs[doc.ID] = doc
+	}
+	return nil
+}
+
+// newDocument monta um Document vazio apontando para o diretório do ID
+func (ws *Workspace) newDocument(id, title string) *Document {
+	return &Document{
+		ID:         id,
+		Dir:        filepath.Join(ws.Root, documentsDir, id),
+		AssetsDir:  filepath.Join(ws.Root, assetsDir),
+		Lint:       ws.Lint,
+		Title:      title,
+		Sections:   []TextSection{},
+		OutputPath: outputName(title),
+	}
+}
+
+// Create cria um novo documento com diretório próprio
+func (ws *Workspace) Create(title string) (*Document, error) {
+	if title == "" {
+		return nil, fmt.Errorf("%w: título vazio", ErrInvalidSection)
+	}
+
+	doc := ws.newDocument(generateID(), title)
+	if err := doc.initDirs(); err != nil {
+		return nil, err
+	}
+	if err := doc.saveManifest(); err != nil {
+		return nil, err
+	}
+
+	ws.mu.Lock()
+	ws.docs[doc.ID] = doc
+	ws.mu.Unlock()
+	return doc, nil
+}
+
+// Get retorna o documento com o ID informado
+func (ws *Workspace) Get(id string) (*Document, error) {
+	ws.mu.RLock()
+	defer ws.mu.RUnlock()
+
+	doc, ok := ws.docs[id]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrDocumentNotFound, id)
+	}
+	return doc, nil
+}
+
+// List retorna os documentos ordenados por título
+func (ws *Workspace) List() []*Document {
+	ws.mu.RLock()
+	docs := make([]*Document, 0, len(ws.docs))
+	for _, doc := range ws.docs {
+		docs = append(docs, doc)
+	}
+	ws.mu.RUnlock()
+
+	infos := make(map[*Document]DocumentInfo, len(docs))
+	for _, doc := range docs {
+		infos[doc] = doc.Info()
+	}
+	sort.Slice(docs, func(i, j int) bool {
+		a, b := infos[docs[i]], infos[docs[j]]
+		if a.Title != b.Title {
+			return a.Title < b.Title
+		}
+		return a.ID < b.ID
+	})
+	return docs
+}
+
+// Rename altera o título do documento. O arquivo de saída mantém o nome
+// escolhido na criação para não quebrar links já publicados.
+func (ws *Workspace) Rename(id, title string) (*Document, error) {
+	if title == "" {
+		return nil, fmt.Errorf("%w: título vazio", ErrInvalidSection)
+	}
+
+	doc, err := ws.Get(id)
+	if err != nil {
+		return nil, err
+	}
+	if err := doc.SetTitle(title); err != nil {
+		return nil, err
+	}
+	return doc, nil
+}
+
+// Delete remove o documento e todo o seu diretório
+func (ws *Workspace) Delete(id string) error {
+	ws.mu.Lock()
+	defer ws.mu.Unlock()
+
+	doc, ok := ws.docs[id]
+	if !ok {
+		return fmt.Errorf("%w: %s", ErrDocumentNotFound, id)
+	}
+	if err := os.RemoveAll(doc.Dir); err != nil {
+		return fmt.Errorf("erro ao remover documento %s: %v", id, err)
+	}
+	delete(ws.docs, id)
+	// quem acompanhava o documento não tem mais o que receber
+	doc.hub().close()
+	return nil
+}
+
+// Flush grava o manifesto de todos os documentos. Cada documento é
+// travado para escrita, então alterações em andamento terminam antes.
+// Usado no encerramento do servidor.
+func (ws *Workspace) Flush() error {
+	ws.mu.RLock()
+	defer ws.mu.RUnlock()
+
+	var errs []error
+	for _, doc := range ws.docs {
+		doc.mu.Lock()
+		err := doc.saveManifest()
+		doc.mu.Unlock()
+		if err != nil {
+			errs = append(errs, fmt.Errorf("documento %s: %v", doc.ID, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// migrateLegacy move um workspace do formato antigo (sections/ e
+// document.json na raiz) para documents/<id>/, preservando as seções.
+// Como a raiz padrão é o diretório atual, só migra quando os arquivos são
+// mesmo do go-writer; pastas alheias com o mesmo nome ficam onde estão.
+func (ws *Workspace) migrateLegacy() error {
+	if !ws.isLegacy() {
+		return nil
+	}
+
+	dir := filepath.Join(ws.Root, documentsDir, generateID())
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+	for _, name := range []string{manifestFile, sectionsDir, outputDir} {
+		from, to := filepath.Join(ws.Root, name), filepath.Join(dir, name)
+		err := os.Rename(from, to)
+		if errors.Is(err, os.ErrNotExist) {
+			continue
+		}
+		if err != nil {
+			return fmt.Errorf("erro ao migrar %s: %v", name, err)
+		}
+		slog.Info("workspace antigo migrado", "from", from, "to", to)
+	}
+	return nil
+}
+
+// isLegacy indica se a raiz tem um documento do formato antigo: um
+// document.json válido e seções em sections/ com o front-matter gravado
+// por saveSection
+func (ws *Workspace) isLegacy() bool {
+	path := filepath.Join(ws.Root, manifestFile)
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return false
+	}
+
+	var m manifest
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&m); err != nil || m.OutputPath == "" {
+		slog.Warn("document.json na raiz não é do go-writer; nada foi migrado", "path", path)
+		return false
+	}
+
+	files, _ := filepath.Glob(filepath.Join(ws.Root, sectionsDir, "*.md"))
+	if len(files) == 0 {
+		slog.Warn("document.json na raiz sem seções; nada foi migrado", "path", path)
+		return false
+	}
+	for _, file := range files {
+		if !hasSectionFrontMatter(file) {
+			slog.Warn("arquivo em sections/ não é do go-writer; nada foi migrado", "path", file)
+			return false
+		}
+	}
+	return true
+}
+
+// outputName deriva o nome do arquivo final a partir do título
+func outputName(title string) string {
+	if name := strings.Trim(githubAnchor(title), "-"); name != "" {
+		return name + ".md"
+	}
+	return "output.md"
+}
@@ -0,0 +1,90 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// writeFiles cria os arquivos em root, com os diretórios necessários
+func writeFiles(t *testing.T, root string, files map[string]string) {
+	t.Helper()
+	for name, content := range files {
+		path := filepath.Join(root, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestMigrateLegacy(t *testing.T) {
+	root := t.TempDir()
+	writeFiles(t, root, map[string]string{
+		"document.json":    `{"title": "Antigo", "outputPath": "antigo.md", "sections": ["a1"]}`,
+		"sections/a1.md":   "---\nid: a1\ntitle: \"Intro\"\norder: 0\nversion: 1\n---\nTexto\n",
+		"output/antigo.md": "# Antigo\n",
+	})
+
+	ws := NewWorkspace(root)
+	if err := initializeWorkspace(ws); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, name := range []string{manifestFile, sectionsDir, outputDir} {
+		if _, err := os.Stat(filepath.Join(root, name)); !os.IsNotExist(err) {
+			t.Errorf("%s continua na raiz depois da migração", name)
+		}
+	}
+	docs := ws.List()
+	if len(docs) != 1 {
+		t.Fatalf("esperado 1 documento migrado, obtidos %d", len(docs))
+	}
+	if got := docs[0].Info(); got.Title != "Antigo" || got.Sections != 1 {
+		t.Errorf("documento migrado = %+v", got)
+	}
+}
+
+func TestMigrateLegacyIgnoresForeignFiles(t *testing.T) {
+	tests := []struct {
+		name  string
+		files map[string]string
+	}{
+		{"só output", map[string]string{
+			"output/relatorio.pdf": "%PDF",
+		}},
+		{"document.json de outro programa", map[string]string{
+			"document.json": `{"name": "outro", "version": 2}`,
+			"sections/a.md": "---\nid: a\n---\n",
+			"output/x.html": "<p>",
+		}},
+		{"sections sem front-matter", map[string]string{
+			"document.json":     `{"title": "T", "outputPath": "t.md", "sections": []}`,
+			"sections/notas.md": "# Minhas notas\n",
+			"output/x.html":     "<p>",
+		}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			root := t.TempDir()
+			writeFiles(t, root, tt.files)
+
+			ws := NewWorkspace(root)
+			if err := initializeWorkspace(ws); err != nil {
+				t.Fatal(err)
+			}
+
+			for name := range tt.files {
+				if _, err := os.Stat(filepath.Join(root, filepath.FromSlash(name))); err != nil {
+					t.Errorf("%s foi movido: %v", name, err)
+				}
+			}
+			if n := len(ws.List()); n != 0 {
+				t.Errorf("esperado nenhum documento, obtidos %d", n)
+			}
+		})
+	}
+}